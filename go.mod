@@ -3,6 +3,26 @@ module httpclient
 go 1.23.2
 
 require (
-	github.com/go-resty/resty/v2 v2.16.2 // indirect
-	golang.org/x/net v0.27.0 // indirect
+	github.com/andybalholm/brotli v1.1.1
+	github.com/go-resty/resty/v2 v2.16.2
+	github.com/klauspost/compress v1.17.9
+)
+
+require github.com/gorilla/websocket v1.5.3
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.20.5
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.27.0
+	golang.org/x/text v0.16.0 // indirect
 )
@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type attemptTimeoutKey struct{}
+
+// withAttemptTimeout returns a context carrying a per-attempt timeout that
+// overrides the client's.
+func withAttemptTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, attemptTimeoutKey{}, d)
+}
+
+// attemptContext bounds one attempt of req by the per-attempt timeout, nested
+// inside the overall request deadline. With a body read timeout the context
+// is cancelable even without a per-attempt timeout.
+func (c *CommonHTTPClient) attemptContext(req *http.Request) (*http.Request, context.CancelFunc) {
+	d := c.attemptTimeout(req)
+	if d <= 0 {
+		if c.bodyReadTimeout > 0 {
+			ctx, cancel := context.WithCancel(req.Context())
+			return req.WithContext(ctx), cancel
+		}
+		return req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	return req.WithContext(ctx), cancel
+}
+
+// attemptTimeout returns the per-attempt timeout for req, 0 for none.
+func (c *CommonHTTPClient) attemptTimeout(req *http.Request) time.Duration {
+	if d, ok := req.Context().Value(attemptTimeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	return c.perAttemptTimeout
+}
+
+// retryFits reports whether ctx leaves time for another attempt of length
+// attempt after delay.
+func retryFits(ctx context.Context, delay, attempt time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > delay+attempt
+}
+
+// sleepContext waits for d or until ctx ends, returning its error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
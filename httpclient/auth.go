@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// RefreshAuthFunc obtains fresh credentials after a 401 response and returns
+// the new Authorization header value, e.g. "Bearer <token>".
+type RefreshAuthFunc func(ctx context.Context) (string, error)
+
+// errEmptyAuth is returned when RefreshAuth returns an empty value.
+var errEmptyAuth = errors.New("httpclient: RefreshAuth returned an empty Authorization value")
+
+// authState holds the current Authorization value shared by derived clients.
+type authState struct {
+	mu    sync.Mutex
+	value string
+}
+
+func (a *authState) get() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// refresh calls fn unless another request already replaced stale with a
+// refreshed value, so a burst of concurrent 401s triggers a single refresh.
+// Credentials from headers never installed by refresh, such as
+// DefaultHeaders, always call fn.
+func (a *authState) refresh(ctx context.Context, stale string, fn RefreshAuthFunc) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.value != "" && a.value != stale {
+		return a.value, nil
+	}
+	value, err := fn(ctx)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", errEmptyAuth
+	}
+	a.value = value
+	return value, nil
+}
+
+// retryUnauthorized refreshes credentials and replays req once after a 401.
+// The original response is returned when the body cannot be replayed or the
+// refresh fails.
+func (c *CommonHTTPClient) retryUnauthorized(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	value, err := c.auth.refresh(req.Context(), req.Header.Get("Authorization"), c.refreshAuth)
+	if err != nil {
+		ev := c.newLogEvent(resp.Request, 0, 0)
+		ev.StatusCode, ev.Err = resp.StatusCode, err
+		c.logEvent(req.Context(), slog.LevelWarn, "Auth refresh failed", ev)
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", value)
+	return c.dispatch(retry)
+}
@@ -0,0 +1,65 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestRefreshAuthWithDefaultHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		refreshed   string
+		wantStatus  int
+		wantAttempt int
+	}{
+		{name: "refreshed token replayed", refreshed: "Bearer new", wantStatus: http.StatusOK, wantAttempt: 2},
+		{name: "empty refresh not replayed", refreshed: "", wantStatus: http.StatusUnauthorized, wantAttempt: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/me", httpmock.MatchHeader("Authorization", "Bearer new")).Reply(http.StatusOK, "{}")
+			mock.On(http.MethodGet, "/me").Reply(http.StatusUnauthorized, "")
+			var refreshes atomic.Int32
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:        base,
+				Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient:     &http.Client{Transport: mock},
+				DefaultHeaders: map[string]string{"Authorization": "Bearer old"},
+				RefreshAuth: func(context.Context) (string, error) {
+					refreshes.Add(1)
+					return tt.refreshed, nil
+				},
+			})
+
+			resp, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/me"})
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := refreshes.Load(); got != 1 {
+				t.Errorf("refresh calls = %d, want 1", got)
+			}
+			calls := mock.Calls()
+			if len(calls) != tt.wantAttempt {
+				t.Fatalf("upstream calls = %d, want %d", len(calls), tt.wantAttempt)
+			}
+			for _, req := range calls {
+				if values := req.Header.Values("Authorization"); len(values) != 1 || values[0] == "" {
+					t.Errorf("Authorization = %q, want one non-empty value", values)
+				}
+			}
+		})
+	}
+}
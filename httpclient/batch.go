@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBatchAborted is recorded for batch requests not sent because a fail-fast
+// batch already had a failure.
+var ErrBatchAborted = errors.New("httpclient: batch aborted")
+
+// BatchOptions configures DoBatch.
+type BatchOptions struct {
+	// Concurrency caps the number of requests in flight (default 8).
+	Concurrency int
+	// FailFast cancels the remaining requests after the first failure.
+	// Otherwise every request runs and failures are only collected.
+	FailFast bool
+	// IsFailure classifies a result; by default only errors are failures, so
+	// set ClientConfig.ErrorOnNon2xx or this to treat statuses as failures.
+	IsFailure func(resp *http.Response, err error) bool
+}
+
+// BatchResult is the outcome of one request of a batch.
+type BatchResult struct {
+	Response *http.Response
+	Err      error
+	Duration time.Duration
+}
+
+// BatchStats aggregates the timing of a batch. Latencies cover only the
+// requests that were sent.
+type BatchStats struct {
+	Succeeded   int
+	Failed      int
+	Aborted     int
+	Wall        time.Duration
+	MinLatency  time.Duration
+	MaxLatency  time.Duration
+	MeanLatency time.Duration
+}
+
+// BatchReport holds the results of DoBatch in request order.
+type BatchReport struct {
+	Results []BatchResult
+	Stats   BatchStats
+}
+
+// BatchError aggregates the failures of a batch by request index.
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	parts := make([]string, len(indexes))
+	for n, i := range indexes {
+		parts[n] = fmt.Sprintf("#%d: %v", i, e.Errors[i])
+	}
+	return fmt.Sprintf("%d batch requests failed: %s", len(indexes), strings.Join(parts, "; "))
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// DoBatch sends reqs concurrently, at most opts.Concurrency at a time, and
+// returns every result in request order. The report is always returned; the
+// error is a *BatchError when any request failed or was aborted. Responses
+// are buffered as with Do, so their bodies may be read after DoBatch returns.
+func (c *CommonHTTPClient) DoBatch(ctx context.Context, reqs []RequestOptions, opts BatchOptions) (*BatchReport, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 8
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(_ *http.Response, err error) bool { return err != nil }
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	start := time.Now()
+	results := make([]BatchResult, len(reqs))
+	sent := make([]bool, len(reqs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.Concurrency, len(reqs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if context.Cause(ctx) == ErrBatchAborted {
+					results[i].Err = ErrBatchAborted
+					continue
+				}
+				sent[i] = true
+				reqStart := time.Now()
+				resp, err := c.Do(ctx, reqs[i])
+				results[i] = BatchResult{Response: resp, Err: err, Duration: time.Since(reqStart)}
+				if opts.FailFast && opts.IsFailure(resp, err) {
+					cancel(ErrBatchAborted)
+				}
+			}
+		}()
+	}
+	for i := range reqs {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	report := &BatchReport{Results: results}
+	report.Stats.Wall = time.Since(start)
+	failures := make(map[int]error)
+	var total time.Duration
+	for i, r := range results {
+		switch {
+		case !sent[i]:
+			report.Stats.Aborted++
+			failures[i] = r.Err
+			continue
+		case opts.IsFailure(r.Response, r.Err):
+			report.Stats.Failed++
+			failures[i] = r.Err
+			if r.Err == nil {
+				failures[i] = fmt.Errorf("status %d", r.Response.StatusCode)
+			}
+		default:
+			report.Stats.Succeeded++
+		}
+		total += r.Duration
+		if report.Stats.MinLatency == 0 || r.Duration < report.Stats.MinLatency {
+			report.Stats.MinLatency = r.Duration
+		}
+		report.Stats.MaxLatency = max(report.Stats.MaxLatency, r.Duration)
+	}
+	if n := report.Stats.Succeeded + report.Stats.Failed; n > 0 {
+		report.Stats.MeanLatency = total / time.Duration(n)
+	}
+	if len(failures) > 0 {
+		return report, &BatchError{Errors: failures}
+	}
+	return report, nil
+}
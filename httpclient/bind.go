@@ -0,0 +1,199 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValidationError reports a request definition field failing a validate rule.
+type ValidationError struct {
+	Field string
+	Rule  string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s fails validation %q", e.Field, e.Rule)
+}
+
+// BuildRequest compiles a declarative request definition into RequestOptions.
+// params must be a struct (or pointer to one) whose fields are tagged with
+// where they go:
+//
+//	type GetPostComments struct {
+//		PostID int    `path:"id" validate:"required"`
+//		Limit  int    `query:"limit" validate:"max=100"`
+//		Tenant string `header:"X-Tenant"`
+//		Filter *Filter `body:"json"`
+//	}
+//
+// Path fields replace "{name}" placeholders in pathTemplate, escaped as a
+// single segment. Nil pointers and zero values are omitted from query and
+// headers; slices are joined with commas. Supported validate rules are
+// "required", "min=N" and "max=N", compared against numeric values or the
+// length of strings and slices. The body tag names a registered codec such as
+// "json", "xml" or a media type (see RegisterCodec).
+func BuildRequest(method, pathTemplate string, params any) (RequestOptions, error) {
+	opts := RequestOptions{Method: method, Path: pathTemplate, RouteTemplate: pathTemplate}
+
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return opts, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return opts, fmt.Errorf("request params must be a struct, got %s", v.Kind())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		if err := validateField(field, value); err != nil {
+			return opts, err
+		}
+
+		if bodyTag, ok := field.Tag.Lookup("body"); ok {
+			if isEmptyValue(value) {
+				continue
+			}
+			if bodyTag == "" {
+				bodyTag = "json"
+			}
+			codec, ok := CodecFor(bodyTag)
+			if !ok {
+				return opts, fmt.Errorf("field %s: unsupported body encoding %q", field.Name, bodyTag)
+			}
+			payload, err := codec.Marshal(value.Interface())
+			if err != nil {
+				return opts, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			opts.Body = bytes.NewReader(payload)
+			setOption(&opts.Headers, "Content-Type", codec.ContentType())
+			continue
+		}
+
+		str, present := formatValue(value)
+		if name := field.Tag.Get("path"); name != "" {
+			placeholder := "{" + name + "}"
+			if !strings.Contains(opts.Path, placeholder) {
+				return opts, fmt.Errorf("field %s: path template has no %s", field.Name, placeholder)
+			}
+			if !present || str == "" {
+				return opts, &ValidationError{Field: field.Name, Rule: "required"}
+			}
+			opts.Path = strings.ReplaceAll(opts.Path, placeholder, escapePathParam(str))
+		} else if name := field.Tag.Get("query"); name != "" && present {
+			setOption(&opts.QueryParams, name, str)
+		} else if name := field.Tag.Get("header"); name != "" && present {
+			setOption(&opts.Headers, name, str)
+		}
+	}
+	return opts, nil
+}
+
+func setOption(m *map[string]string, k, v string) {
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[k] = v
+}
+
+// formatValue renders a field for use in a path, query or header. present is
+// false for nil pointers and zero values.
+func formatValue(v reflect.Value) (s string, present bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		s, _ = formatValue(v.Elem())
+		return s, true
+	}
+	if v.IsZero() {
+		return "", false
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			part, _ := formatValue(v.Index(i))
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ","), true
+	default:
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String(), true
+		}
+		return fmt.Sprint(v.Interface()), true
+	}
+}
+
+func validateField(field reflect.StructField, v reflect.Value) error {
+	rules := field.Tag.Get("validate")
+	if rules == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if isEmptyValue(v) {
+				return &ValidationError{Field: field.Name, Rule: rule}
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return fmt.Errorf("field %s: invalid rule %q", field.Name, rule)
+			}
+			size, ok := measure(v)
+			if !ok {
+				continue
+			}
+			if (name == "min" && size < limit) || (name == "max" && size > limit) {
+				return &ValidationError{Field: field.Name, Rule: rule}
+			}
+		default:
+			return fmt.Errorf("field %s: unknown rule %q", field.Name, rule)
+		}
+	}
+	return nil
+}
+
+// measure returns the number compared by min/max rules. Nil pointers are
+// skipped so optional fields only validate when set.
+func measure(v reflect.Value) (float64, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,280 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a per-host circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText renders the state name, e.g. in JSON introspection output.
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// BreakerConfig configures the breakers of a BreakerRegistry.
+type BreakerConfig struct {
+	// ConsecutiveFailures trips the breaker after this many failures in a row
+	// (default 5).
+	ConsecutiveFailures int
+	// FailureRate, if > 0, also trips the breaker when the share of failed
+	// attempts within Window reaches it, once MinRequests were seen.
+	FailureRate float64
+	MinRequests int
+	Window      time.Duration
+	// Cooldown is how long the breaker stays open before letting a single
+	// probe through in the half-open state (default 30s).
+	Cooldown time.Duration
+	// IsFailure classifies an attempt; by default transport errors and 5xx
+	// responses are failures. Attempts ended by the caller's own context,
+	// cancelled or past its deadline, are never counted.
+	IsFailure func(resp *http.Response, err error) bool
+}
+
+// BreakerRegistry holds one circuit breaker per destination host. Share a
+// registry between clients to share breaker state for the same hosts.
+type BreakerRegistry struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewBreakerRegistry creates a registry applying cfg to every host.
+func NewBreakerRegistry(cfg BreakerConfig) *BreakerRegistry {
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		}
+	}
+	return &BreakerRegistry{cfg: cfg, breakers: make(map[string]*circuitBreaker)}
+}
+
+// State returns the breaker state for host.
+func (r *BreakerRegistry) State(host string) BreakerState {
+	b := r.get(host)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState(time.Now())
+}
+
+// AnyOpen reports whether any host breaker is open, e.g. to feed
+// SchedulerConfig.Pressure.
+func (r *BreakerRegistry) AnyOpen() bool {
+	r.mu.Lock()
+	breakers := make([]*circuitBreaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		breakers = append(breakers, b)
+	}
+	r.mu.Unlock()
+
+	for _, b := range breakers {
+		b.mu.Lock()
+		open := b.state == BreakerOpen
+		b.mu.Unlock()
+		if open {
+			return true
+		}
+	}
+	return false
+}
+
+// States returns the current breaker state of every host seen so far.
+func (r *BreakerRegistry) States() map[string]BreakerState {
+	r.mu.Lock()
+	breakers := make(map[string]*circuitBreaker, len(r.breakers))
+	for host, b := range r.breakers {
+		breakers[host] = b
+	}
+	r.mu.Unlock()
+
+	now := time.Now()
+	states := make(map[string]BreakerState, len(breakers))
+	for host, b := range breakers {
+		b.mu.Lock()
+		states[host] = b.currentState(now)
+		b.mu.Unlock()
+	}
+	return states
+}
+
+func (r *BreakerRegistry) get(host string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.breakers[host]
+	if !ok {
+		b = &circuitBreaker{cfg: &r.cfg}
+		r.breakers[host] = b
+	}
+	return b
+}
+
+// check fails fast while host's breaker rejects attempts, without taking
+// the half-open probe slot.
+func (r *BreakerRegistry) check(host string) error {
+	if err := r.get(host).check(time.Now()); err != nil {
+		return fmt.Errorf("%w for host %s", err, host)
+	}
+	return nil
+}
+
+// allow checks whether an attempt to host may proceed, taking the probe slot
+// of a half-open breaker. Every allowed attempt must be passed to record.
+func (r *BreakerRegistry) allow(host string) error {
+	if err := r.get(host).allow(time.Now()); err != nil {
+		return fmt.Errorf("%w for host %s", err, host)
+	}
+	return nil
+}
+
+// record reports the outcome of an attempt to host made for ctx. When ctx
+// ended the attempt the outcome says nothing about the host; only a probe
+// slot is released.
+func (r *BreakerRegistry) record(ctx context.Context, host string, resp *http.Response, err error) {
+	b := r.get(host)
+	if err != nil && ctx.Err() != nil {
+		b.release()
+		return
+	}
+	b.record(time.Now(), r.cfg.IsFailure(resp, err))
+}
+
+type circuitBreaker struct {
+	cfg *BreakerConfig
+
+	mu          sync.Mutex
+	state       BreakerState
+	openedAt    time.Time
+	probing     bool
+	consecutive int
+	windowStart time.Time
+	total       int
+	failures    int
+}
+
+// currentState moves an open breaker to half-open once the cooldown passed.
+func (b *circuitBreaker) currentState(now time.Time) BreakerState {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cfg.Cooldown {
+		b.state = BreakerHalfOpen
+		b.probing = false
+	}
+	return b.state
+}
+
+func (b *circuitBreaker) check(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentState(now) {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+	}
+	return nil
+}
+
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentState(now) {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// release frees the probe slot of a half-open breaker after an attempt that
+// did not reach a verdict.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) record(now time.Time, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		if failed {
+			b.trip(now)
+		} else {
+			b.reset(now)
+		}
+		return
+	}
+
+	if now.Sub(b.windowStart) > b.cfg.Window {
+		b.windowStart, b.total, b.failures = now, 0, 0
+	}
+	b.total++
+	if !failed {
+		b.consecutive = 0
+		return
+	}
+	b.failures++
+	b.consecutive++
+
+	rateTripped := b.cfg.FailureRate > 0 && b.total >= b.cfg.MinRequests &&
+		float64(b.failures)/float64(b.total) >= b.cfg.FailureRate
+	if b.consecutive >= b.cfg.ConsecutiveFailures || rateTripped {
+		b.trip(now)
+	}
+}
+
+func (b *circuitBreaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.probing = false
+}
+
+func (b *circuitBreaker) reset(now time.Time) {
+	b.state = BreakerClosed
+	b.probing = false
+	b.consecutive = 0
+	b.windowStart, b.total, b.failures = now, 0, 0
+}
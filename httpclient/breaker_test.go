@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func newBreakerTestClient(t *testing.T, mock *httpmock.Mock, breakers *BreakerRegistry, limits ...RateLimit) *CommonHTTPClient {
+	t.Helper()
+	base, _ := url.Parse("http://api.test")
+	return NewCommonHTTPClient(ClientConfig{
+		BaseURL:    base,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HTTPClient: &http.Client{Transport: mock},
+		Breakers:   breakers,
+		RateLimits: limits,
+	})
+}
+
+// tripBreaker fails one request to /fail and waits out the cooldown, leaving
+// the breaker half-open.
+func tripBreaker(t *testing.T, c *CommonHTTPClient, breakers *BreakerRegistry, cooldown time.Duration) {
+	t.Helper()
+	if _, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/fail"}); err != nil {
+		t.Fatalf("tripping request: %v", err)
+	}
+	if got := breakers.State("api.test"); got != BreakerOpen {
+		t.Fatalf("state after failure = %s, want open", got)
+	}
+	time.Sleep(cooldown + 10*time.Millisecond)
+	if got := breakers.State("api.test"); got != BreakerHalfOpen {
+		t.Fatalf("state after cooldown = %s, want half-open", got)
+	}
+}
+
+func TestBreakerHalfOpenProbeReleasedOnEarlyExit(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	tests := []struct {
+		name   string
+		limit  []RateLimit
+		before func(c *CommonHTTPClient)
+		probe  func(c *CommonHTTPClient) error
+	}{
+		{
+			name:  "rate limiter wait cancelled",
+			limit: []RateLimit{{Pattern: "/limited", RPS: 0.001, Burst: 1}},
+			before: func(c *CommonHTTPClient) {
+				// Use up the only token
+				c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/limited"})
+			},
+			probe: func(c *CommonHTTPClient) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := c.Do(ctx, RequestOptions{Method: http.MethodGet, Path: "/limited"})
+				return err
+			},
+		},
+		{
+			name: "request hook error",
+			probe: func(c *CommonHTTPClient) error {
+				hooks := &Hooks{OnRequest: func(*http.Request) error { return errors.New("no token") }}
+				_, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/ok", Hooks: hooks})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/fail").Reply(http.StatusInternalServerError, "")
+			mock.On(http.MethodGet, "/ok").Reply(http.StatusOK, "")
+			breakers := NewBreakerRegistry(BreakerConfig{ConsecutiveFailures: 1, Cooldown: cooldown})
+			mock.On(http.MethodGet, "/limited").Reply(http.StatusOK, "")
+			c := newBreakerTestClient(t, mock, breakers, tt.limit...)
+			if tt.before != nil {
+				tt.before(c)
+			}
+
+			tripBreaker(t, c, breakers, cooldown)
+			if err := tt.probe(c); err == nil {
+				t.Fatal("probe succeeded, want an early exit")
+			}
+
+			resp, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/ok"})
+			if err != nil {
+				t.Fatalf("request after aborted probe: %v", err)
+			}
+			resp.Body.Close()
+			if got := breakers.State("api.test"); got != BreakerClosed {
+				t.Errorf("state = %s, want closed", got)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenTransitions(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+	tests := []struct {
+		name   string
+		status int
+		want   BreakerState
+	}{
+		{name: "successful probe closes", status: http.StatusOK, want: BreakerClosed},
+		{name: "failed probe reopens", status: http.StatusBadGateway, want: BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/fail").Reply(http.StatusInternalServerError, "")
+			mock.On(http.MethodGet, "/probe").Reply(tt.status, "")
+			breakers := NewBreakerRegistry(BreakerConfig{ConsecutiveFailures: 1, Cooldown: cooldown})
+			c := newBreakerTestClient(t, mock, breakers)
+
+			tripBreaker(t, c, breakers, cooldown)
+			if _, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/probe"}); err != nil {
+				t.Fatalf("probe: %v", err)
+			}
+			if got := breakers.State("api.test"); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresCallerContextErrors(t *testing.T) {
+	mock := httpmock.New()
+	mock.On(http.MethodGet, "/slow").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	breakers := NewBreakerRegistry(BreakerConfig{ConsecutiveFailures: 1})
+	c := newBreakerTestClient(t, mock, breakers)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, RequestOptions{Method: http.MethodGet, Path: "/slow"}); err == nil {
+		t.Fatal("request succeeded, want the context error")
+	}
+	if got := breakers.State("api.test"); got != BreakerClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+}
@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// capabilitiesTTL is how long discovered capabilities are cached.
+const capabilitiesTTL = 5 * time.Minute
+
+// Capabilities describes what a server supports for a resource, as
+// discovered by an OPTIONS request or, failing that, a HEAD probe.
+type Capabilities struct {
+	// Allow lists permitted methods; empty when the server did not say.
+	Allow []string
+	// AcceptPatch lists media types accepted by PATCH.
+	AcceptPatch []string
+	// AcceptRanges is "bytes" when range requests are supported.
+	AcceptRanges string
+	// CORS response headers, if the server sent any.
+	CORSAllowOrigin  string
+	CORSAllowMethods []string
+	CORSAllowHeaders []string
+	// Probe is the method used for discovery, OPTIONS or HEAD.
+	Probe     string
+	Header    http.Header
+	FetchedAt time.Time
+}
+
+// Allows reports whether method is listed in Allow. It returns true when the
+// server did not advertise allowed methods.
+func (c *Capabilities) Allows(method string) bool {
+	if len(c.Allow) == 0 {
+		return true
+	}
+	for _, m := range c.Allow {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilityCache caches Capabilities by host and path.
+type capabilityCache struct {
+	mu      sync.Mutex
+	entries map[string]*Capabilities
+}
+
+// Capabilities discovers and caches what the server supports for path. It
+// issues OPTIONS and falls back to HEAD when OPTIONS is not implemented.
+func (c *CommonHTTPClient) Capabilities(ctx context.Context, path string) (*Capabilities, error) {
+	key := c.scopedPath(path)
+	if c.baseURL != nil {
+		key = c.baseURL.Host + " " + key
+	}
+
+	c.capabilities.mu.Lock()
+	cached, ok := c.capabilities.entries[key]
+	c.capabilities.mu.Unlock()
+	if ok && time.Since(cached.FetchedAt) < capabilitiesTTL {
+		return cached, nil
+	}
+
+	caps, err := c.probeCapabilities(ctx, path, http.MethodOptions)
+	if err == nil && caps == nil {
+		// OPTIONS is not implemented; HEAD still reveals headers like Accept-Ranges
+		caps, err = c.probeCapabilities(ctx, path, http.MethodHead)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if caps == nil {
+		caps = &Capabilities{Probe: http.MethodHead, FetchedAt: time.Now()}
+	}
+
+	c.capabilities.mu.Lock()
+	c.capabilities.entries[key] = caps
+	c.capabilities.mu.Unlock()
+	return caps, nil
+}
+
+// InvalidateCapabilities drops all cached capabilities.
+func (c *CommonHTTPClient) InvalidateCapabilities() {
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	c.capabilities.entries = make(map[string]*Capabilities)
+}
+
+// probeCapabilities returns nil capabilities when the probe method is not
+// supported by the server.
+func (c *CommonHTTPClient) probeCapabilities(ctx context.Context, path, method string) (*Capabilities, error) {
+	resp, err := c.Do(ctx, RequestOptions{Method: method, Path: path})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		return nil, nil
+	}
+
+	h := resp.Header
+	return &Capabilities{
+		Allow:            splitHeaderList(h.Values("Allow")),
+		AcceptPatch:      splitHeaderList(h.Values("Accept-Patch")),
+		AcceptRanges:     h.Get("Accept-Ranges"),
+		CORSAllowOrigin:  h.Get("Access-Control-Allow-Origin"),
+		CORSAllowMethods: splitHeaderList(h.Values("Access-Control-Allow-Methods")),
+		CORSAllowHeaders: splitHeaderList(h.Values("Access-Control-Allow-Headers")),
+		Probe:            method,
+		Header:           h,
+		FetchedAt:        time.Now(),
+	}, nil
+}
+
+// splitHeaderList flattens comma-separated header values.
+func splitHeaderList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+
+	"httpclient/metrics"
+	"httpclient/utils"
+)
+
+// ChecksumError is returned while reading a response body whose content does
+// not match the checksum announced by the server.
+type ChecksumError struct {
+	URL       string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s: %s checksum mismatch: expected %s, got %s", e.URL, e.Algorithm, e.Expected, e.Actual)
+}
+
+// checksumAlgorithm maps a checksum header to its hash. Values are base64
+// encoded digests, as sent by S3-compatible servers.
+type checksumAlgorithm struct {
+	name   string
+	header string
+	hash   func() hash.Hash
+}
+
+// checksumAlgorithms is in order of preference.
+var checksumAlgorithms = []checksumAlgorithm{
+	{"sha256", "X-Amz-Checksum-Sha256", sha256.New},
+	{"sha1", "X-Amz-Checksum-Sha1", sha1.New},
+	{"crc32c", "X-Amz-Checksum-Crc32c", func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }},
+	{"crc32", "X-Amz-Checksum-Crc32", func() hash.Hash { return crc32.NewIEEE() }},
+	{"md5", "Content-Md5", md5.New},
+}
+
+// verifyChecksum wraps resp.Body so that reaching EOF checks it against a
+// checksum header or trailer. Partial and transparently decompressed
+// responses are left alone, as their checksums describe other bytes.
+func (c *CommonHTTPClient) verifyChecksum(resp *http.Response) {
+	if resp.Body == nil || resp.Body == http.NoBody || resp.Uncompressed || resp.StatusCode == http.StatusPartialContent {
+		return
+	}
+	for _, alg := range checksumAlgorithms {
+		_, trailer := resp.Trailer[alg.header]
+		if trailer || resp.Header.Get(alg.header) != "" {
+			resp.Body = &checksumReader{ReadCloser: resp.Body, resp: resp, alg: alg, hash: alg.hash(), recorder: c.metrics, redactor: c.redactor}
+			return
+		}
+	}
+}
+
+// checksumReader hashes a body as it is read and verifies it at EOF.
+type checksumReader struct {
+	io.ReadCloser
+	resp     *http.Response
+	alg      checksumAlgorithm
+	hash     hash.Hash
+	recorder metrics.Recorder
+	redactor *utils.Redactor
+	err      error
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if verr := r.verify(); verr != nil {
+			err = verr
+		}
+		r.err = err
+	}
+	return n, err
+}
+
+// verify compares the digest with the header, or the trailer which is only
+// available once the body has been read.
+func (r *checksumReader) verify() error {
+	expected := r.resp.Header.Get(r.alg.header)
+	if v := r.resp.Trailer.Get(r.alg.header); v != "" {
+		expected = v
+	}
+	if expected == "" {
+		return nil
+	}
+	actual := base64.StdEncoding.EncodeToString(r.hash.Sum(nil))
+	outcome := "ok"
+	if actual != expected {
+		outcome = "mismatch"
+	}
+	if rec, ok := r.recorder.(metrics.ChecksumRecorder); ok {
+		rec.ObserveChecksum(r.alg.name, outcome)
+	}
+	if outcome != "ok" {
+		return &ChecksumError{URL: r.redactor.URL(r.resp.Request.URL), Algorithm: r.alg.name, Expected: expected, Actual: actual}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestChecksumErrorURLRedacted(t *testing.T) {
+	mock := httpmock.New()
+	mock.On(http.MethodGet, "/file").Reply(http.StatusOK, "payload").Header("Content-Md5", "AAAAAAAAAAAAAAAAAAAAAA==")
+	base, _ := url.Parse("http://api.test")
+	c := NewCommonHTTPClient(ClientConfig{
+		BaseURL:           base,
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HTTPClient:        &http.Client{Transport: mock},
+		VerifyChecksums:   true,
+		RedactQueryParams: []string{"token"},
+	})
+
+	resp, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/file", QueryParams: map[string]string{"token": "secret"}})
+	if err == nil {
+		// Streamed bodies fail once read to the end
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("read error = %v, want *ChecksumError", err)
+	}
+	if strings.Contains(checksumErr.Error(), "secret") || !strings.Contains(checksumErr.URL, "token=****") {
+		t.Errorf("URL = %s, want the token redacted", checksumErr.URL)
+	}
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Clock returns the current time. Timestamp-sensitive signers accept a Clock
+// so they can use server-corrected time from a ClockSkew.
+type Clock func() time.Time
+
+// ClockSkew estimates the offset between the local clock and the servers'
+// clocks from response Date headers. Its Now method returns local time
+// corrected by that offset, preventing "signature expired" or "token used
+// before issued" failures on hosts whose clocks drift. Feed it responses by
+// installing client.Use(skew.Middleware()).
+type ClockSkew struct {
+	// MaxRTT discards samples from slow round trips, whose midpoint says little
+	// about when the server stamped the response (default 5s).
+	MaxRTT time.Duration
+
+	mu      sync.Mutex
+	offset  time.Duration
+	samples int
+}
+
+// skewSmoothing weighs new samples in the moving average. Date headers only
+// have one-second resolution, so individual samples are noisy.
+const skewSmoothing = 0.2
+
+// Offset returns the estimated server time minus local time.
+func (s *ClockSkew) Offset() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// Now returns the local time corrected by the estimated offset. It can be
+// used directly as a Clock.
+func (s *ClockSkew) Now() time.Time {
+	return time.Now().Add(s.Offset())
+}
+
+// Observe records a sample from a response whose request was sent at sent and
+// received at received.
+func (s *ClockSkew) Observe(resp *http.Response, sent, received time.Time) {
+	maxRTT := s.MaxRTT
+	if maxRTT <= 0 {
+		maxRTT = 5 * time.Second
+	}
+	rtt := received.Sub(sent)
+	if rtt > maxRTT {
+		return
+	}
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// Date is truncated to the second; assume the middle of that second
+	sample := serverTime.Add(500 * time.Millisecond).Sub(sent.Add(rtt / 2))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samples == 0 {
+		s.offset = sample
+	} else {
+		s.offset += time.Duration(float64(sample-s.offset) * skewSmoothing)
+	}
+	s.samples++
+}
+
+// Middleware returns a Middleware feeding every response into the estimate.
+func (s *ClockSkew) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			sent := time.Now()
+			resp, err := next.Do(req)
+			if err == nil {
+				s.Observe(resp, sent, time.Now())
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec marshals request bodies and unmarshals response bodies for one
+// media type. Register codecs with RegisterCodec; msgpack or CBOR support is
+// a thin Codec around the library of choice.
+type Codec interface {
+	// ContentType is the media type sent in Content-Type and Accept.
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string                { return "application/json" }
+func (JSONCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// XMLCodec encodes with encoding/xml.
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string                { return "application/xml" }
+func (XMLCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (XMLCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+// ProtobufCodec encodes binary protobuf; values must be proto.Message.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// codecs is the registry used by Send, Decode and BuildRequest, keyed by
+// media type and by short name ("json", "xml", "protobuf").
+var codecs = struct {
+	sync.RWMutex
+	byType map[string]Codec
+}{byType: map[string]Codec{
+	"application/json":       JSONCodec{},
+	"json":                   JSONCodec{},
+	"application/xml":        XMLCodec{},
+	"text/xml":               XMLCodec{},
+	"xml":                    XMLCodec{},
+	"application/x-protobuf": ProtobufCodec{},
+	"application/protobuf":   ProtobufCodec{},
+	"protobuf":               ProtobufCodec{},
+}}
+
+// RegisterCodec makes codec available under its ContentType and the given
+// aliases, which may be other media types or short names for BuildRequest
+// body tags. It replaces any codec registered for the same keys.
+func RegisterCodec(codec Codec, aliases ...string) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	for _, key := range append([]string{codec.ContentType()}, aliases...) {
+		codecs.byType[strings.ToLower(key)] = codec
+	}
+}
+
+// CodecFor returns the codec for a Content-Type value or short name.
+// Parameters are ignored, and structured syntax suffixes fall back to their
+// base format, so "application/problem+json" uses the JSON codec.
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+		mediaType = parsed
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if codec, ok := codecs.byType[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		codec, ok := codecs.byType[mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
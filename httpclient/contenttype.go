@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxContentTypeSnippet is how much of an unexpected body is kept for errors.
+const maxContentTypeSnippet = 512
+
+// ContentTypeError is returned when a response Content-Type is not one of the
+// expected media types, e.g. an HTML error page where JSON was expected.
+type ContentTypeError struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+	Expected    []string
+	// Snippet holds the beginning of the response body for diagnostics.
+	Snippet string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q from %s (status %d, expected %s): %s",
+		e.ContentType, e.URL, e.StatusCode, strings.Join(e.Expected, ", "), e.Snippet)
+}
+
+// checkContentType validates resp against the expected media types. The body
+// is read for the snippet and restored so the response stays usable.
+func (c *CommonHTTPClient) checkContentType(resp *http.Response, expected []string) error {
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, want := range expected {
+			if mediaTypeMatches(mediaType, want) {
+				return nil
+			}
+		}
+	}
+
+	var snippet []byte
+	if resp.Body != nil {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		snippet = body
+		if len(snippet) > maxContentTypeSnippet {
+			snippet = snippet[:maxContentTypeSnippet]
+		}
+	}
+
+	var reqURL string
+	if resp.Request != nil {
+		reqURL = c.redactor.URL(resp.Request.URL)
+	}
+
+	return &ContentTypeError{
+		URL:         reqURL,
+		StatusCode:  resp.StatusCode,
+		ContentType: contentType,
+		Expected:    expected,
+		Snippet:     string(snippet),
+	}
+}
+
+// mediaTypeMatches reports whether mediaType satisfies pattern. A pattern
+// subtype of "*" (e.g. "text/*") accepts any subtype.
+func mediaTypeMatches(mediaType, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == mediaType || pattern == "*/*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return false
+}
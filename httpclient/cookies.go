@@ -0,0 +1,220 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// CookieStore persists the cookies of a CookieJar, e.g. in a file or a
+// shared cache, so login sessions survive restarts.
+type CookieStore interface {
+	// LoadCookies returns the data last passed to SaveCookies, or nil when
+	// nothing was saved yet.
+	LoadCookies() ([]byte, error)
+	SaveCookies(data []byte) error
+}
+
+// FileCookieStore stores cookies as JSON in the file at its path.
+type FileCookieStore string
+
+func (f FileCookieStore) LoadCookies() ([]byte, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (f FileCookieStore) SaveCookies(data []byte) error {
+	// Session cookies are credentials
+	return os.WriteFile(string(f), data, 0o600)
+}
+
+// CookieJarConfig configures a CookieJar.
+type CookieJarConfig struct {
+	// Store, when set, provides the initial cookies and is updated whenever
+	// a response changes them.
+	Store CookieStore
+	// PublicSuffixList restricts the domains servers may set cookies for; see
+	// net/http/cookiejar.
+	PublicSuffixList cookiejar.PublicSuffixList
+	// OnSaveError receives errors from saving to Store, which would
+	// otherwise be discarded.
+	OnSaveError func(error)
+}
+
+// CookieJar is an http.CookieJar, set as ClientConfig.CookieJar, that can
+// save and restore its cookies. Cookies are kept by net/http/cookiejar; the
+// jar additionally remembers the Set-Cookie values it accepted, which are
+// replayed against their original URLs on restore.
+type CookieJar struct {
+	jar *cookiejar.Jar
+	cfg CookieJarConfig
+
+	mu      sync.Mutex
+	cookies map[string]savedCookie
+}
+
+// savedCookie is a cookie as received from the server at URL.
+type savedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Expires  time.Time     `json:"expires"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+// NewCookieJar creates a jar holding the cookies in cfg.Store, if any.
+func NewCookieJar(cfg CookieJarConfig) (*CookieJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: cfg.PublicSuffixList})
+	if err != nil {
+		return nil, err
+	}
+	j := &CookieJar{jar: jar, cfg: cfg, cookies: make(map[string]savedCookie)}
+	if cfg.Store == nil {
+		return j, nil
+	}
+	data, err := cfg.Store.LoadCookies()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := j.Unmarshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar and saves the cookies to the store.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+	j.setCookies(u, cookies)
+	if j.cfg.Store == nil {
+		return
+	}
+	err := j.Save()
+	if err != nil && j.cfg.OnSaveError != nil {
+		j.cfg.OnSaveError(err)
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	jar := j.jar
+	j.mu.Unlock()
+	return jar.Cookies(u)
+}
+
+// Save writes the current cookies to the store.
+func (j *CookieJar) Save() error {
+	if j.cfg.Store == nil {
+		return nil
+	}
+	data, err := j.Marshal()
+	if err != nil {
+		return err
+	}
+	return j.cfg.Store.SaveCookies(data)
+}
+
+// Marshal returns the unexpired cookies as JSON, for stores of their own.
+func (j *CookieJar) Marshal() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	out := make([]savedCookie, 0, len(j.cookies))
+	for key, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		out = append(out, c)
+	}
+	return json.Marshal(out)
+}
+
+// Unmarshal adds the cookies in data, produced by Marshal, to the jar.
+func (j *CookieJar) Unmarshal(data []byte) error {
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	for _, c := range saved {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return err
+		}
+		j.setCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}})
+	}
+	return nil
+}
+
+// Clear drops all cookies, e.g. on logout, and saves the empty jar.
+func (j *CookieJar) Clear() error {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: j.cfg.PublicSuffixList})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.jar = jar
+	j.cookies = make(map[string]savedCookie)
+	j.mu.Unlock()
+	return j.Save()
+}
+
+func (j *CookieJar) setCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+	origin := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	now := time.Now()
+	for _, c := range cookies {
+		key := u.Hostname() + ";" + c.Domain + ";" + c.Path + ";" + c.Name
+		expires := c.Expires
+		switch {
+		case c.MaxAge < 0:
+			expires = now
+		case c.MaxAge > 0:
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if !expires.IsZero() && !expires.After(now) {
+			// The server deleted the cookie
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = savedCookie{
+			URL:      origin.String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned without contacting the server once the
+// client's Budget for the current window is spent.
+var ErrBudgetExceeded = errors.New("httpclient: API budget exceeded")
+
+// CostEstimator returns the cost of one attempt, e.g. 1 per call or a price
+// derived from a response header. resp is nil when the attempt failed without
+// a response.
+type CostEstimator func(req *http.Request, resp *http.Response) float64
+
+// Budget caps the cost spent per fixed window, e.g. 1000 paid calls per hour,
+// to stop runaway loops against metered APIs. The attempt that crosses the
+// limit still completes; later attempts fail with ErrBudgetExceeded until the
+// window resets.
+type Budget struct {
+	Limit  float64
+	Window time.Duration
+}
+
+// CostStats reports spending in Stats.
+type CostStats struct {
+	// Total is the cost of all attempts since the client was created.
+	Total float64
+	// Window is the cost spent in the current budget window.
+	Window float64
+	// Rejected counts attempts refused by the budget.
+	Rejected int64
+}
+
+// costTracker accounts attempt costs and enforces an optional budget.
+type costTracker struct {
+	estimator CostEstimator
+	budget    *Budget
+
+	mu          sync.Mutex
+	stats       CostStats
+	windowStart time.Time
+}
+
+// newCostTracker returns nil when neither an estimator nor a budget is set.
+// Budgets without an estimator count each attempt as 1.
+func newCostTracker(estimator CostEstimator, budget *Budget) *costTracker {
+	if estimator == nil && budget == nil {
+		return nil
+	}
+	if estimator == nil {
+		estimator = func(*http.Request, *http.Response) float64 { return 1 }
+	}
+	return &costTracker{estimator: estimator, budget: budget}
+}
+
+// allow reports whether another attempt fits in the budget.
+func (t *costTracker) allow() error {
+	if t == nil || t.budget == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollWindow(time.Now())
+	if t.stats.Window >= t.budget.Limit {
+		t.stats.Rejected++
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// record adds the cost of one attempt.
+func (t *costTracker) record(req *http.Request, resp *http.Response) {
+	if t == nil {
+		return
+	}
+	cost := t.estimator(req, resp)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollWindow(time.Now())
+	t.stats.Total += cost
+	t.stats.Window += cost
+}
+
+// rollWindow starts a new budget window once the current one has elapsed.
+func (t *costTracker) rollWindow(now time.Time) {
+	if t.budget == nil || t.budget.Window <= 0 {
+		return
+	}
+	if now.Sub(t.windowStart) >= t.budget.Window {
+		t.windowStart = now
+		t.stats.Window = 0
+	}
+}
+
+func (t *costTracker) snapshot() CostStats {
+	if t == nil {
+		return CostStats{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollWindow(time.Now())
+	return t.stats
+}
@@ -0,0 +1,63 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineFormat selects how the remaining deadline is encoded in DeadlineHeader.
+type DeadlineFormat int
+
+const (
+	// DeadlineMillis sends the remaining time as integer milliseconds.
+	DeadlineMillis DeadlineFormat = iota
+	// DeadlineGRPC sends the remaining time in grpc-timeout form, e.g. "250m".
+	DeadlineGRPC
+)
+
+// setDeadlineHeader writes the time left until the request context deadline.
+// Requests without a deadline, or clients without DeadlineHeader, are untouched.
+func (c *CommonHTTPClient) setDeadlineHeader(req *http.Request) {
+	if c.deadlineHeader == "" {
+		return
+	}
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return
+	}
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	switch c.deadlineFormat {
+	case DeadlineGRPC:
+		req.Header.Set(c.deadlineHeader, formatGRPCTimeout(remaining))
+	default:
+		req.Header.Set(c.deadlineHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+	}
+}
+
+// formatGRPCTimeout encodes d using the finest grpc-timeout unit whose value
+// fits in the eight digits the gRPC spec allows.
+func formatGRPCTimeout(d time.Duration) string {
+	const maxValue = 99999999
+	units := []struct {
+		size   time.Duration
+		suffix string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+		{time.Hour, "H"},
+	}
+	for _, u := range units {
+		if v := int64(d / u.size); v <= maxValue {
+			return strconv.FormatInt(v, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(maxValue, 10) + "H"
+}
@@ -0,0 +1,172 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrResponseTooLarge matches, via errors.Is, every *ResponseTooLargeError.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ResponseTooLargeError is returned when a response body exceeds
+// ClientConfig.MaxResponseBodyBytes. The limit applies to the decoded body.
+type ResponseTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s: response body exceeds %d bytes", e.URL, e.Limit)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// Content codings supported by ClientConfig.AcceptEncodings.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+)
+
+// decoders open a decoding reader for each supported content coding.
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	EncodingGzip: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	EncodingDeflate: func(r io.Reader) (io.ReadCloser, error) {
+		return newDeflateReader(r)
+	},
+	EncodingBrotli: func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	EncodingZstd: func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	},
+}
+
+// newDeflateReader reads "deflate" bodies, which should be zlib-wrapped but
+// are raw DEFLATE streams on some servers.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	var head [2]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	r = io.MultiReader(bytes.NewReader(head[:n]), r)
+	// A zlib header is CMF/FLG with CM 8 and a checksum divisible by 31
+	if n == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		return zlib.NewReader(r)
+	}
+	return flate.NewReader(r), nil
+}
+
+// acceptEncoding returns the Accept-Encoding value for the configured codings.
+func (c *CommonHTTPClient) acceptEncoding() string {
+	if c.disableDecompress {
+		return ""
+	}
+	return strings.Join(c.acceptEncodings, ", ")
+}
+
+// decompress replaces the body of resp with its decoded form when it uses one
+// of the client's AcceptEncodings. Content-Encoding and Content-Length are
+// removed, like net/http does for transparent gzip.
+func (c *CommonHTTPClient) decompress(resp *http.Response) error {
+	if c.disableDecompress || len(c.acceptEncodings) == 0 || resp.Body == nil {
+		return nil
+	}
+	coding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if coding == "" || coding == "identity" || !containsFold(c.acceptEncodings, coding) {
+		return nil
+	}
+	open, ok := decoders[coding]
+	if !ok {
+		return nil
+	}
+	body, err := open(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("decode %s response: %w", coding, err)
+	}
+	resp.Body = &decodedBody{ReadCloser: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody closes both the decoder and the underlying body.
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
+// limitBody makes reads of resp beyond MaxResponseBodyBytes fail with
+// *ResponseTooLargeError. A larger Content-Length fails right away.
+func (c *CommonHTTPClient) limitBody(resp *http.Response) error {
+	if c.maxResponseBytes <= 0 || resp.Body == nil {
+		return nil
+	}
+	tooLarge := &ResponseTooLargeError{URL: c.redactor.URL(resp.Request.URL), Limit: c.maxResponseBytes}
+	if resp.ContentLength > c.maxResponseBytes {
+		resp.Body.Close()
+		return tooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: c.maxResponseBytes, err: tooLarge}
+	return nil
+}
+
+// limitedBody returns err once more than remaining bytes would be read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	// Read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.err
+	}
+	return n, err
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
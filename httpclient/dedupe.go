@@ -0,0 +1,148 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupeConfig enables request deduplication: concurrent identical GET
+// requests share one upstream call and each caller gets its own copy of the
+// response. Requests are identical when their URL, tenant, credentials
+// (Authorization, Proxy-Authorization and Cookie) and VaryHeaders match.
+type DedupeConfig struct {
+	// VaryHeaders are request headers that distinguish otherwise identical
+	// requests, e.g. "Authorization" or "Accept-Language".
+	VaryHeaders []string
+	// Window additionally shares a completed response with requests arriving
+	// this long after it finished; it is dropped once the window ends. Zero
+	// shares in-flight calls only.
+	Window time.Duration
+}
+
+// dedupeGroup is a singleflight keyed by request identity.
+type dedupeGroup struct {
+	cfg DedupeConfig
+
+	mu    sync.Mutex
+	calls map[string]*dedupeCall
+}
+
+type dedupeCall struct {
+	done   chan struct{}
+	resp   *http.Response
+	body   []byte
+	err    error
+	expiry time.Time
+}
+
+func newDedupeGroup(cfg *DedupeConfig) *dedupeGroup {
+	if cfg == nil {
+		return nil
+	}
+	return &dedupeGroup{cfg: *cfg, calls: make(map[string]*dedupeCall)}
+}
+
+// key identifies req among requests that may share a response. Like the
+// response cache key, it never lets callers with different credentials or
+// tenants share a response.
+func (g *dedupeGroup) key(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, TenantFromContext(req.Context()))
+	for _, names := range [][]string{credentialHeaders, g.cfg.VaryHeaders} {
+		for _, name := range names {
+			io.WriteString(h, "\n"+http.CanonicalHeaderKey(name)+": "+strings.Join(req.Header.Values(name), ","))
+		}
+	}
+	return req.URL.String() + "\x00" + hex.EncodeToString(h.Sum(nil))
+}
+
+// middleware shares GET calls between identical concurrent requests. The
+// shared call is not cancelled with the request that started it, so the
+// other callers still get its response. Streamed requests always go upstream
+// on their own.
+func (g *dedupeGroup) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet || isStreaming(req.Context()) {
+			return next.Do(req)
+		}
+		key := g.key(req)
+
+		g.mu.Lock()
+		if call, ok := g.calls[key]; ok {
+			select {
+			case <-call.done:
+				if time.Now().After(call.expiry) {
+					delete(g.calls, key)
+					break
+				}
+				g.mu.Unlock()
+				return call.result(req)
+			default:
+				g.mu.Unlock()
+				return call.wait(req)
+			}
+		}
+		call := &dedupeCall{done: make(chan struct{})}
+		g.calls[key] = call
+		g.mu.Unlock()
+
+		go g.run(key, call, next, req.WithContext(context.WithoutCancel(req.Context())))
+		return call.wait(req)
+	})
+}
+
+// run makes the shared call and drops it from the group when its window
+// ends.
+func (g *dedupeGroup) run(key string, call *dedupeCall, next Doer, req *http.Request) {
+	call.resp, call.err = next.Do(req)
+	if call.err == nil {
+		call.body, call.err = io.ReadAll(call.resp.Body)
+		call.resp.Body.Close()
+	}
+	call.expiry = time.Now().Add(g.cfg.Window)
+	close(call.done)
+	if g.cfg.Window <= 0 {
+		g.forget(key, call)
+		return
+	}
+	time.AfterFunc(g.cfg.Window, func() { g.forget(key, call) })
+}
+
+// forget drops call unless a newer call has taken its key.
+func (g *dedupeGroup) forget(key string, call *dedupeCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// wait returns the result of c once it completes, or the error of req's
+// context if that ends first.
+func (c *dedupeCall) wait(req *http.Request) (*http.Response, error) {
+	select {
+	case <-c.done:
+		return c.result(req)
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// result returns a private copy of the shared response for req.
+func (c *dedupeCall) result(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	resp.Request = req
+	return &resp, nil
+}
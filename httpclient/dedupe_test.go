@@ -0,0 +1,185 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+// dedupeDoer sends requests straight to mock.
+func dedupeDoer(mock *httpmock.Mock) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return mock.RoundTrip(req)
+	})
+}
+
+func (g *dedupeGroup) size() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
+
+func TestDedupeEviction(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		// kept reports whether the call is still shared right after it ends.
+		kept bool
+	}{
+		{name: "in-flight only", window: 0, kept: false},
+		{name: "window", window: 30 * time.Millisecond, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/items").Reply(http.StatusOK, "[]")
+			g := newDedupeGroup(&DedupeConfig{Window: tt.window})
+			doer := g.middleware(dedupeDoer(mock))
+
+			req, _ := http.NewRequest(http.MethodGet, "http://api.test/items", nil)
+			resp, err := doer.Do(req)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+
+			// run drops in-flight-only calls right after answering the leader
+			time.Sleep(5 * time.Millisecond)
+			if got, want := g.size(), map[bool]int{false: 0, true: 1}[tt.kept]; got != want {
+				t.Errorf("calls after completion = %d, want %d", got, want)
+			}
+			// No further request arrives; the entry must go on its own
+			time.Sleep(tt.window + 20*time.Millisecond)
+			if got := g.size(); got != 0 {
+				t.Errorf("calls after window = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestDedupeLeaderCancelDoesNotFailFollowers(t *testing.T) {
+	release := make(chan struct{})
+	mock := httpmock.New()
+	mock.On(http.MethodGet, "/items").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(http.NoBody),
+			Request:    req,
+		}, nil
+	})
+	// The window shares the outcome even with a follower scheduled late
+	g := newDedupeGroup(&DedupeConfig{Window: time.Minute})
+	doer := g.middleware(dedupeDoer(mock))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderReq, _ := http.NewRequestWithContext(leaderCtx, http.MethodGet, "http://api.test/items", nil)
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := doer.Do(leaderReq)
+		leaderErr <- err
+	}()
+	for g.size() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	followerReq, _ := http.NewRequest(http.MethodGet, "http://api.test/items", nil)
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	follower := make(chan result, 1)
+	go func() {
+		resp, err := doer.Do(followerReq)
+		follower <- result{resp, err}
+	}()
+
+	cancelLeader()
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	close(release)
+	res := <-follower
+	if res.err != nil {
+		t.Fatalf("follower error = %v, want the shared response", res.err)
+	}
+	res.resp.Body.Close()
+	if got := len(mock.Calls()); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+}
+
+func TestDedupeKeyedByCaller(t *testing.T) {
+	tests := []struct {
+		name         string
+		first, other func(req *http.Request) *http.Request
+		wantCalls    int
+	}{
+		{
+			name:      "same Authorization",
+			first:     withHeader("Authorization", "Bearer a"),
+			other:     withHeader("Authorization", "Bearer a"),
+			wantCalls: 1,
+		},
+		{
+			name:      "other Authorization",
+			first:     withHeader("Authorization", "Bearer a"),
+			other:     withHeader("Authorization", "Bearer b"),
+			wantCalls: 2,
+		},
+		{
+			name:      "other cookie",
+			first:     withHeader("Cookie", "session=a"),
+			other:     withHeader("Cookie", "session=b"),
+			wantCalls: 2,
+		},
+		{
+			name:      "other tenant",
+			first:     withTenantContext("acme"),
+			other:     withTenantContext("globex"),
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/items").Reply(http.StatusOK, "[]")
+			g := newDedupeGroup(&DedupeConfig{Window: time.Minute})
+			doer := g.middleware(dedupeDoer(mock))
+
+			for _, prepare := range []func(*http.Request) *http.Request{tt.first, tt.other} {
+				req, _ := http.NewRequest(http.MethodGet, "http://api.test/items", nil)
+				resp, err := doer.Do(prepare(req))
+				if err != nil {
+					t.Fatalf("Do: %v", err)
+				}
+				resp.Body.Close()
+			}
+			if got := len(mock.Calls()); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func withHeader(name, value string) func(*http.Request) *http.Request {
+	return func(req *http.Request) *http.Request {
+		req.Header.Set(name, value)
+		return req
+	}
+}
+
+func withTenantContext(id string) func(*http.Request) *http.Request {
+	return func(req *http.Request) *http.Request {
+		return req.WithContext(WithTenant(req.Context(), id))
+	}
+}
@@ -0,0 +1,63 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// defaultClient backs the package-level helpers. It starts with a zero config
+// (slog.Default logger, 30s timeout, no retries) and can be replaced at any time.
+var defaultClient atomic.Pointer[CommonHTTPClient]
+
+func init() {
+	defaultClient.Store(NewCommonHTTPClient(ClientConfig{}))
+}
+
+// DefaultClient returns the client used by the package-level helpers.
+func DefaultClient() *CommonHTTPClient {
+	return defaultClient.Load()
+}
+
+// SetDefaultClient replaces the client used by the package-level helpers.
+// It is safe to call concurrently with in-flight requests.
+func SetDefaultClient(c *CommonHTTPClient) {
+	if c == nil {
+		c = NewCommonHTTPClient(ClientConfig{})
+	}
+	defaultClient.Store(c)
+}
+
+// Do executes opts with the default client.
+func Do(ctx context.Context, opts RequestOptions) (*http.Response, error) {
+	return DefaultClient().Do(ctx, opts)
+}
+
+// Get issues a GET to url with the default client. url may be absolute or,
+// when the default client has a base URL, a path relative to it.
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	return Do(ctx, RequestOptions{Method: http.MethodGet, Path: url})
+}
+
+// Post issues a POST to url with the given content type and body using the
+// default client.
+func Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	return Do(ctx, RequestOptions{
+		Method:  http.MethodPost,
+		Path:    url,
+		Headers: map[string]string{"Content-Type": contentType},
+		Body:    body,
+	})
+}
+
+// DoJSON executes opts with the default client and decodes the JSON response
+// into v. The response body is closed; the response is returned for its status
+// and headers.
+func DoJSON(ctx context.Context, opts RequestOptions, v interface{}) (*http.Response, error) {
+	resp, err := Do(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return resp, DecodeJSONResponse(resp, v)
+}
@@ -0,0 +1,212 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint is one instance of a service. Priority and Weight follow DNS SRV:
+// the lowest priority is used while it has instances, and weight spreads
+// requests among them.
+type Endpoint struct {
+	Host     string
+	Port     int
+	Priority int
+	Weight   int
+}
+
+// EndpointProvider lists the instances of a logical service.
+type EndpointProvider interface {
+	Resolve(ctx context.Context, service string) ([]Endpoint, error)
+}
+
+// StaticEndpoints is an EndpointProvider backed by a fixed list per service,
+// e.g. for tests or environments without a registry.
+type StaticEndpoints map[string][]Endpoint
+
+// Resolve implements EndpointProvider.
+func (s StaticEndpoints) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	endpoints, ok := s[service]
+	if !ok || len(endpoints) == 0 {
+		return nil, fmt.Errorf("discovery: no endpoints for service %q", service)
+	}
+	return endpoints, nil
+}
+
+// SRVEndpoints is an EndpointProvider looking up DNS SRV records, where the
+// service is the full record name, e.g. "_api._tcp.example.com" or
+// "payments.service.consul".
+type SRVEndpoints struct {
+	// Resolver defaults to net.DefaultResolver; set its Dial to query a
+	// specific server such as Consul DNS.
+	Resolver *net.Resolver
+}
+
+// Resolve implements EndpointProvider.
+func (s *SRVEndpoints) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	resolver := s.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, "", "", service)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(records))
+	for _, r := range records {
+		endpoints = append(endpoints, Endpoint{
+			Host:     strings.TrimSuffix(r.Target, "."),
+			Port:     int(r.Port),
+			Priority: int(r.Priority),
+			Weight:   int(r.Weight),
+		})
+	}
+	return endpoints, nil
+}
+
+// DiscoveryConfig configures a Discovery.
+type DiscoveryConfig struct {
+	Provider EndpointProvider
+	// RefreshInterval is how long resolved endpoints are reused (default 30s).
+	// When a refresh fails, the previous endpoints stay in use.
+	RefreshInterval time.Duration
+	// Logger reports failed refreshes (default slog.Default()).
+	Logger *slog.Logger
+}
+
+// Discovery resolves logical service names to endpoints for
+// ClientConfig.Discovery, caching them between refreshes.
+type Discovery struct {
+	cfg      DiscoveryConfig
+	mu       sync.Mutex
+	services map[string]*discoveredService
+}
+
+type discoveredService struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+	refreshed time.Time
+}
+
+// NewDiscovery creates a Discovery applying cfg.
+func NewDiscovery(cfg DiscoveryConfig) *Discovery {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Discovery{cfg: cfg, services: make(map[string]*discoveredService)}
+}
+
+// Endpoints returns the endpoints of service, resolving them when the cached
+// list is older than the refresh interval.
+func (d *Discovery) Endpoints(ctx context.Context, service string) ([]Endpoint, error) {
+	d.mu.Lock()
+	s, ok := d.services[service]
+	if !ok {
+		s = &discoveredService{}
+		d.services[service] = s
+	}
+	d.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints != nil && time.Since(s.refreshed) < d.cfg.RefreshInterval {
+		return s.endpoints, nil
+	}
+	endpoints, err := d.cfg.Provider.Resolve(ctx, service)
+	if err == nil && len(endpoints) == 0 {
+		err = fmt.Errorf("discovery: no endpoints for service %q", service)
+	}
+	if err != nil {
+		if s.endpoints == nil {
+			return nil, err
+		}
+		d.cfg.Logger.Warn("Service discovery refresh failed, keeping previous endpoints",
+			slog.String("service", service),
+			slog.Any("error", err),
+		)
+		// Retry on the next interval rather than on every request
+		s.refreshed = time.Now()
+		return s.endpoints, nil
+	}
+	s.endpoints, s.refreshed = endpoints, time.Now()
+	return endpoints, nil
+}
+
+// Pick selects an endpoint of service: among those with the lowest priority,
+// at random in proportion to their weights.
+func (d *Discovery) Pick(ctx context.Context, service string) (Endpoint, error) {
+	endpoints, err := d.Endpoints(ctx, service)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	best := endpoints[0].Priority
+	for _, e := range endpoints {
+		best = min(best, e.Priority)
+	}
+	var candidates []Endpoint
+	total := 0
+	for _, e := range endpoints {
+		if e.Priority == best {
+			candidates = append(candidates, e)
+			total += e.Weight
+		}
+	}
+	if total == 0 {
+		return candidates[rand.IntN(len(candidates))], nil
+	}
+	n := rand.IntN(total)
+	for _, e := range candidates {
+		if n -= e.Weight; n < 0 {
+			return e, nil
+		}
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// Invalidate drops the cached endpoints of service, or of all services when
+// service is empty, so the next request resolves them again.
+func (d *Discovery) Invalidate(service string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if service == "" {
+		d.services = make(map[string]*discoveredService)
+		return
+	}
+	delete(d.services, service)
+}
+
+// discover points an attempt for the base URL host at an endpoint of that
+// service. Other hosts, e.g. absolute URLs, are left alone.
+func (c *CommonHTTPClient) discover(req *http.Request) (*http.Request, error) {
+	if c.discovery == nil || c.baseURL == nil || req.URL.Host != c.baseURL.Host {
+		return req, nil
+	}
+	e, err := c.discovery.Pick(req.Context(), c.baseURL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	port := req.URL.Port()
+	if e.Port > 0 {
+		port = strconv.Itoa(e.Port)
+	}
+	u := *req.URL
+	u.Host = e.Host
+	if port != "" {
+		u.Host = net.JoinHostPort(e.Host, port)
+	}
+	out := *req
+	out.URL = &u
+	out.Host = ""
+	return &out, nil
+}
@@ -0,0 +1,382 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver looks up the addresses of a host and how long they may be cached.
+type Resolver interface {
+	Resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error)
+}
+
+// SystemResolver resolves through the system resolver, which does not report
+// record TTLs; results are cached for TTL (default 30s).
+type SystemResolver struct {
+	// Resolver defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+	TTL      time.Duration
+}
+
+// Resolve implements Resolver.
+func (r *SystemResolver) Resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ttl := r.TTL
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	for i, addr := range addrs {
+		addrs[i] = addr.Unmap()
+	}
+	return addrs, ttl, err
+}
+
+// DNSServerResolver queries one DNS server directly, e.g. Consul DNS at
+// "127.0.0.1:8600", and honors the TTLs of the returned records. Queries go
+// over UDP and are repeated over TCP when the answer is truncated.
+type DNSServerResolver struct {
+	// Server is the host:port of the DNS server.
+	Server string
+	// Timeout bounds each query (default 2s).
+	Timeout time.Duration
+}
+
+// Resolve implements Resolver, querying A and AAAA records concurrently. The
+// TTL is the lowest among the answers.
+func (r *DNSServerResolver) Resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		addrs []netip.Addr
+		ttl   time.Duration
+		err   error
+	}
+	results := make(chan result, 2)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		go func() {
+			addrs, ttl, err := r.query(ctx, host, qtype)
+			results <- result{addrs, ttl, err}
+		}()
+	}
+
+	var addrs []netip.Addr
+	var ttl time.Duration = -1
+	var errs []error
+	for range 2 {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		addrs = append(addrs, res.addrs...)
+		if len(res.addrs) > 0 && (ttl < 0 || res.ttl < ttl) {
+			ttl = res.ttl
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) > 0 {
+			return nil, 0, errors.Join(errs...)
+		}
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: r.Server, IsNotFound: true}
+	}
+	return addrs, ttl, nil
+}
+
+// query sends one question and returns the matching addresses.
+func (r *DNSServerResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]netip.Addr, time.Duration, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.N(1 << 16))
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	answer, err := r.exchange(ctx, "udp", query, id)
+	if err == nil && answer.Truncated {
+		answer, err = r.exchange(ctx, "tcp", query, id)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if answer.RCode == dnsmessage.RCodeNameError {
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: r.Server, IsNotFound: true}
+	}
+	if answer.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, &net.DNSError{Err: "server error: " + answer.RCode.String(), Name: host, Server: r.Server}
+	}
+
+	// CNAME chains are followed by the server; take every address answer
+	var addrs []netip.Addr
+	var ttl time.Duration = -1
+	for _, rr := range answer.Answers {
+		var addr netip.Addr
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			addr = netip.AddrFrom4(body.A)
+		case *dnsmessage.AAAAResource:
+			addr = netip.AddrFrom16(body.AAAA)
+		default:
+			continue
+		}
+		addrs = append(addrs, addr)
+		if d := time.Duration(rr.Header.TTL) * time.Second; ttl < 0 || d < ttl {
+			ttl = d
+		}
+	}
+	return addrs, max(ttl, 0), nil
+}
+
+// exchange sends query over network and reads the response with the same ID.
+func (r *DNSServerResolver) exchange(ctx context.Context, network string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, r.Server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	buf := make([]byte, 65535)
+	if network == "tcp" {
+		// TCP messages carry a two-byte length prefix
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+		if _, err := conn.Write(append(framed, query...)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint16(buf[:2])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	} else {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			var h dnsmessage.Header
+			var p dnsmessage.Parser
+			if h, err = p.Start(buf[:n]); err == nil && h.ID == id && h.Response {
+				buf = buf[:n]
+				break
+			}
+			// Ignore stray or spoofed datagrams
+		}
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("dns: %w", err)
+	}
+	if msg.ID != id {
+		return nil, errors.New("dns: response ID mismatch")
+	}
+	return &msg, nil
+}
+
+func dnsFQDN(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
+
+// DNSCacheConfig configures a DNSCache.
+type DNSCacheConfig struct {
+	// Resolver performs lookups (default &SystemResolver{}).
+	Resolver Resolver
+	// MinTTL and MaxTTL clamp the TTLs reported by the resolver (defaults 1s
+	// and 5m), so a TTL of 0 does not disable caching.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// NegativeTTL caches failed lookups for this long (default 0, not cached).
+	NegativeTTL time.Duration
+}
+
+// DNSCacheStats counts cache activity since creation or the last Flush.
+type DNSCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Errors  int64 `json:"errors"`
+	Entries int   `json:"entries"`
+}
+
+// DNSCache caches host lookups in process for ClientConfig.DNSCache.
+// Concurrent lookups of one host share a single query. Share a cache between
+// clients to share its entries.
+type DNSCache struct {
+	cfg     DNSCacheConfig
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	hits    atomic.Int64
+	misses  atomic.Int64
+	errors  atomic.Int64
+}
+
+type dnsEntry struct {
+	addrs   []netip.Addr
+	err     error
+	expires time.Time
+	// ready is closed once the lookup finished
+	ready chan struct{}
+}
+
+// NewDNSCache creates a cache applying cfg.
+func NewDNSCache(cfg DNSCacheConfig) *DNSCache {
+	if cfg.Resolver == nil {
+		cfg.Resolver = &SystemResolver{}
+	}
+	if cfg.MinTTL <= 0 {
+		cfg.MinTTL = time.Second
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = 5 * time.Minute
+	}
+	return &DNSCache{cfg: cfg, entries: make(map[string]*dnsEntry)}
+}
+
+// Lookup returns the addresses of host, from the cache while they are fresh.
+// IP literals are returned as-is.
+func (d *DNSCache) Lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	d.mu.Lock()
+	e, ok := d.entries[host]
+	if ok {
+		select {
+		case <-e.ready:
+			if time.Now().After(e.expires) {
+				ok = false
+			}
+		default:
+			// A lookup is in flight; wait for it below
+		}
+	}
+	if ok {
+		d.mu.Unlock()
+		d.hits.Add(1)
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return e.addrs, e.err
+	}
+	e = &dnsEntry{ready: make(chan struct{})}
+	d.entries[host] = e
+	d.mu.Unlock()
+	d.misses.Add(1)
+
+	// Detach from the caller so one canceled request does not fail the
+	// lookup for everyone waiting on it
+	addrs, ttl, err := d.cfg.Resolver.Resolve(context.WithoutCancel(ctx), host)
+	e.addrs, e.err = addrs, err
+	if err != nil {
+		d.errors.Add(1)
+		e.expires = time.Now().Add(d.cfg.NegativeTTL)
+		if d.cfg.NegativeTTL <= 0 {
+			d.mu.Lock()
+			if d.entries[host] == e {
+				delete(d.entries, host)
+			}
+			d.mu.Unlock()
+		}
+	} else {
+		e.expires = time.Now().Add(min(max(ttl, d.cfg.MinTTL), d.cfg.MaxTTL))
+	}
+	close(e.ready)
+	return addrs, err
+}
+
+// Flush drops all entries and resets the stats, e.g. after a failover.
+func (d *DNSCache) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = make(map[string]*dnsEntry)
+	d.hits.Store(0)
+	d.misses.Store(0)
+	d.errors.Store(0)
+}
+
+// Stats returns the cache counters and the number of cached hosts.
+func (d *DNSCache) Stats() DNSCacheStats {
+	d.mu.Lock()
+	entries := len(d.entries)
+	d.mu.Unlock()
+	return DNSCacheStats{
+		Hits:    d.hits.Load(),
+		Misses:  d.misses.Load(),
+		Errors:  d.errors.Load(),
+		Entries: entries,
+	}
+}
+
+// dialContext resolves the host through the cache and dials its addresses
+// in turn with dial until one connects.
+func (d *DNSCache) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := d.Lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var errs []error
+		for _, ip := range addrs {
+			if (network == "tcp4" && !ip.Unmap().Is4()) || (network == "tcp6" && ip.Unmap().Is4()) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if len(errs) == 0 {
+			return nil, &net.DNSError{Err: "no suitable address", Name: host}
+		}
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,235 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DownloadOptions configures Download.
+type DownloadOptions struct {
+	// Resume continues a previous partial download from "<path>.part" using a
+	// Range request. The ETag or Last-Modified of the first response is kept
+	// in "<path>.part.validator" and sent as If-Range, so a resource changed
+	// since then is downloaded again from the beginning instead of spliced
+	// onto the stale part. Part files without a validator are not resumed.
+	// Servers ignoring the range restart from the beginning.
+	Resume bool
+	// SHA256 is the expected hex digest of the complete file, if known.
+	SHA256 string
+	// Progress, if set, is called as data is written.
+	Progress func(DownloadProgress)
+}
+
+// DownloadProgress reports download progress. Total is -1 when unknown.
+type DownloadProgress struct {
+	Written int64
+	Total   int64
+}
+
+// IntegrityError is returned when a downloaded file does not match its
+// expected size or checksum. The partial file is removed.
+type IntegrityError struct {
+	Path     string
+	Kind     string
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("download %s: %s mismatch: expected %s, got %s", e.Path, e.Kind, e.Expected, e.Actual)
+}
+
+// Download streams the response of opts (GET by default) to path without
+// buffering it in memory. Data is written to "<path>.part" and renamed into
+// place once Content-Length and the optional checksum are verified.
+func (c *CommonHTTPClient) Download(ctx context.Context, opts RequestOptions, path string, dl DownloadOptions) error {
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+	opts.StreamResponse = true
+	partPath := path + ".part"
+
+	var offset int64
+	var validator string
+	if dl.Resume {
+		if info, err := os.Stat(partPath); err == nil {
+			offset = info.Size()
+		}
+		validator = readValidator(partPath)
+	}
+	if offset > 0 && validator != "" {
+		headers := make(map[string]string, len(opts.Headers)+3)
+		for k, v := range opts.Headers {
+			headers[k] = v
+		}
+		headers["Range"] = RangeFrom(offset).String()
+		headers["If-Range"] = validator
+		// Offsets refer to the unencoded representation
+		headers["Accept-Encoding"] = "identity"
+		opts.Headers = headers
+	} else {
+		offset = 0
+	}
+
+	resp, err := c.Do(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		cr, err := ParseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || cr.Start != offset {
+			return fmt.Errorf("download %s: unexpected Content-Range %q", path, resp.Header.Get("Content-Range"))
+		}
+		total = cr.Size
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The part file may already be complete
+		if cr, err := ParseContentRange(resp.Header.Get("Content-Range")); err == nil && cr.Size == offset {
+			return c.finishDownload(partPath, path, offset, offset, dl, nil)
+		}
+		return fmt.Errorf("download %s: range not satisfiable", path)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		offset = 0
+		flags |= os.O_TRUNC
+		total = ParseResourceInfo(resp).Size
+		if err := writeValidator(partPath, resp); err != nil {
+			return err
+		}
+	default:
+		return c.newAPIError(resp)
+	}
+	if total < 0 && resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	f, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var hasher hash.Hash
+	if dl.SHA256 != "" {
+		hasher = sha256.New()
+		if offset > 0 {
+			if err := hashPrefix(partPath, offset, hasher); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+
+	var w io.Writer = f
+	if hasher != nil {
+		w = io.MultiWriter(f, hasher)
+	}
+	pw := &progressWriter{w: w, written: offset, total: total, progress: dl.Progress}
+	_, copyErr := io.Copy(pw, resp.Body)
+	closeErr := f.Close()
+	if copyErr != nil {
+		// Keep the part file so a later call can resume
+		return copyErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return c.finishDownload(partPath, path, pw.written, total, dl, hasher)
+}
+
+// finishDownload verifies size and checksum and moves the part file into place.
+func (c *CommonHTTPClient) finishDownload(partPath, path string, written, total int64, dl DownloadOptions, hasher hash.Hash) error {
+	if total >= 0 && written != total {
+		os.Remove(partPath)
+		os.Remove(partPath + validatorSuffix)
+		return &IntegrityError{Path: path, Kind: "size", Expected: strconv.FormatInt(total, 10), Actual: strconv.FormatInt(written, 10)}
+	}
+	if dl.SHA256 != "" {
+		if hasher == nil {
+			hasher = sha256.New()
+			if err := hashPrefix(partPath, written, hasher); err != nil {
+				return err
+			}
+		}
+		actual := hex.EncodeToString(hasher.Sum(nil))
+		if !strings.EqualFold(actual, dl.SHA256) {
+			os.Remove(partPath)
+			os.Remove(partPath + validatorSuffix)
+			return &IntegrityError{Path: path, Kind: "sha256", Expected: dl.SHA256, Actual: actual}
+		}
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return err
+	}
+	os.Remove(partPath + validatorSuffix)
+	return nil
+}
+
+// validatorSuffix names the file next to a part file that holds its If-Range
+// validator.
+const validatorSuffix = ".validator"
+
+// readValidator returns the validator stored for partPath, or "" if none.
+func readValidator(partPath string) string {
+	b, err := os.ReadFile(partPath + validatorSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// writeValidator stores the strong ETag of resp, or else its Last-Modified
+// date, for resuming partPath. Weak ETags cannot be used in If-Range, so a
+// response with neither leaves no validator and is not resumed.
+func writeValidator(partPath string, resp *http.Response) error {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(partPath + validatorSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(partPath+validatorSuffix, []byte(validator), 0o644)
+}
+
+// hashPrefix feeds the first n bytes of the file at path into h.
+func hashPrefix(path string, n int64, h hash.Hash) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(h, f, n)
+	return err
+}
+
+// progressWriter counts written bytes and reports progress.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(DownloadProgress)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(DownloadProgress{Written: p.written, Total: p.total})
+	}
+	return n, err
+}
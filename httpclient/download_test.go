@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+// failingBody returns its content and then an error, like a dropped connection.
+type failingBody struct {
+	r io.Reader
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func (b *failingBody) Close() error { return nil }
+
+func TestDownloadResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator string
+		// stub registers the server's answers
+		stub        func(mock *httpmock.Mock)
+		wantHeaders map[string]string
+		want        string
+	}{
+		{
+			name:      "unchanged resource resumed",
+			validator: `"v1"`,
+			stub: func(mock *httpmock.Mock) {
+				mock.On(http.MethodGet, "/file", httpmock.MatchHeader("If-Range", `"v1"`)).
+					Reply(http.StatusPartialContent, "world").
+					Header("Content-Range", "bytes 6-10/11")
+			},
+			wantHeaders: map[string]string{"Range": "bytes=6-", "If-Range": `"v1"`, "Accept-Encoding": "identity"},
+			want:        "hello world",
+		},
+		{
+			name:      "changed resource downloaded again",
+			validator: `"v1"`,
+			stub: func(mock *httpmock.Mock) {
+				mock.On(http.MethodGet, "/file").Reply(http.StatusOK, "fresh content").Header("ETag", `"v2"`)
+			},
+			wantHeaders: map[string]string{"Range": "bytes=6-", "If-Range": `"v1"`},
+			want:        "fresh content",
+		},
+		{
+			name: "part file without validator not resumed",
+			stub: func(mock *httpmock.Mock) {
+				mock.On(http.MethodGet, "/file").Reply(http.StatusOK, "fresh content")
+			},
+			wantHeaders: map[string]string{"Range": "", "If-Range": ""},
+			want:        "fresh content",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			tt.stub(mock)
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:    base,
+				Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient: &http.Client{Transport: mock},
+			})
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path+".part", []byte("hello "), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.validator != "" {
+				if err := os.WriteFile(path+".part.validator", []byte(tt.validator), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := c.Download(context.Background(), RequestOptions{Path: "/file"}, path, DownloadOptions{Resume: true})
+			if err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+			calls := mock.Calls()
+			if len(calls) != 1 {
+				t.Fatalf("upstream calls = %d, want 1", len(calls))
+			}
+			for k, want := range tt.wantHeaders {
+				if got := calls[0].Header.Get(k); got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(path + ".part.validator"); !os.IsNotExist(err) {
+				t.Errorf("validator left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadStoresValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   string
+	}{
+		{name: "strong ETag", header: map[string]string{"ETag": `"v1"`, "Last-Modified": "Mon, 02 Jan 2006 15:04:05 GMT"}, want: `"v1"`},
+		{name: "weak ETag", header: map[string]string{"ETag": `W/"v1"`, "Last-Modified": "Mon, 02 Jan 2006 15:04:05 GMT"}, want: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{name: "no validator", header: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/file").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+				h := make(http.Header)
+				for k, v := range tt.header {
+					h.Set(k, v)
+				}
+				return &http.Response{
+					StatusCode:    http.StatusOK,
+					Header:        h,
+					ContentLength: 11,
+					Body:          &failingBody{r: strings.NewReader("hello ")},
+					Request:       req,
+				}, nil
+			})
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:    base,
+				Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient: &http.Client{Transport: mock},
+			})
+			path := filepath.Join(t.TempDir(), "file")
+
+			if err := c.Download(context.Background(), RequestOptions{Path: "/file"}, path, DownloadOptions{}); err == nil {
+				t.Fatal("Download succeeded, want the read error")
+			}
+			if got := readValidator(path + ".part"); got != tt.want {
+				t.Errorf("stored validator = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"httpclient/utils"
+)
+
+// defaultRedactor masks utils.DefaultRedactHeaders for dumps made without a
+// client.
+var defaultRedactor = utils.NewRedactor(nil, nil, nil)
+
+// DumpCurl renders req as an equivalent curl command for reproducing it by
+// hand, with utils.DefaultRedactHeaders masked. A body without GetBody is
+// read and replaced, so req can still be sent afterwards.
+func DumpCurl(req *http.Request) (string, error) {
+	return dumpCurl(req, defaultRedactor)
+}
+
+// DumpCurl renders req as a curl command like the package-level DumpCurl,
+// applying the client's redaction rules.
+func (c *CommonHTTPClient) DumpCurl(req *http.Request) (string, error) {
+	return dumpCurl(req, c.redactor)
+}
+
+func dumpCurl(req *http.Request, redactor *utils.Redactor) (string, error) {
+	body, err := peekRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{"curl"}
+	if req.Method != "" && req.Method != http.MethodGet {
+		parts = append(parts, "-X", req.Method)
+	}
+	parts = append(parts, shellQuote(redactor.URL(req.URL)))
+
+	headers := redactor.Headers(req.Header)
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if req.Host != "" && req.Host != req.URL.Host {
+		parts = append(parts, "-H", shellQuote("Host: "+req.Host))
+	}
+	for _, name := range names {
+		for _, v := range headers[name] {
+			parts = append(parts, "-H", shellQuote(name+": "+v))
+		}
+	}
+	if len(body) > 0 {
+		parts = append(parts, "--data-raw", shellQuote(redactor.Body(string(body))))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// peekRequestBody returns the body of req without consuming it, through
+// GetBody when set and otherwise by buffering and replacing req.Body.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		return readRequestBody(req)
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,314 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointPolicy declares the per-route policy of one named endpoint in a
+// single block, keeping it out of call sites. Requests select a policy like
+// RateLimit profiles: by endpoint Name first (see RequestOptions.Endpoint),
+// then by path.Match Pattern against the URL path.
+type EndpointPolicy struct {
+	Name    string
+	Pattern string
+	// CacheTTL caches successful GET responses in memory for this long. HEAD
+	// requests for a cached URL are answered from the same entry. Entries
+	// are kept per caller, see ClientConfig.CacheKeyHeaders, and honor the
+	// Vary response header. Responses marked Cache-Control: no-store or
+	// Vary: * are never cached.
+	CacheTTL time.Duration
+	// NegativeCacheTTL overrides ClientConfig.NegativeCacheTTL.
+	NegativeCacheTTL time.Duration
+	// Retry overrides the client retry settings.
+	Retry *RetryProfile
+	// RPS and Burst add a rate limit, as in RateLimit.
+	RPS   float64
+	Burst int
+	// Fallback serves degraded responses, unless the request sets its own.
+	Fallback FallbackFunc
+}
+
+// RetryProfile sets how often and how fast a request is retried.
+type RetryProfile struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// endpointCatalog compiles EndpointPolicy declarations.
+type endpointCatalog struct {
+	policies []EndpointPolicy
+	cache    *responseCache
+}
+
+// negativeCaching reports whether any policy caches missing resources.
+func (e *endpointCatalog) negativeCaching() bool {
+	if e == nil {
+		return false
+	}
+	for _, p := range e.policies {
+		if p.NegativeCacheTTL > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newEndpointCatalog returns nil when no policies are declared.
+func newEndpointCatalog(policies []EndpointPolicy, keyHeaders []string) *endpointCatalog {
+	if len(policies) == 0 {
+		return nil
+	}
+	return &endpointCatalog{policies: policies, cache: newResponseCache(keyHeaders)}
+}
+
+// rateLimits returns the rate limit profiles declared by the catalog.
+func (e *endpointCatalog) rateLimits() []RateLimit {
+	if e == nil {
+		return nil
+	}
+	var limits []RateLimit
+	for _, p := range e.policies {
+		if p.RPS > 0 {
+			limits = append(limits, RateLimit{Name: p.Name, Pattern: p.Pattern, RPS: p.RPS, Burst: p.Burst})
+		}
+	}
+	return limits
+}
+
+// match returns the policy for req, or nil.
+func (e *endpointCatalog) match(req *http.Request) *EndpointPolicy {
+	if e == nil {
+		return nil
+	}
+	endpoint := EndpointFromContext(req.Context())
+	for i, p := range e.policies {
+		if endpoint != "" && p.Name == endpoint {
+			return &e.policies[i]
+		}
+	}
+	for i, p := range e.policies {
+		if p.Pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(p.Pattern, req.URL.Path); ok {
+			return &e.policies[i]
+		}
+	}
+	return nil
+}
+
+// retryProfile returns the retry settings for req, with the request's
+// overrides applied.
+func (c *CommonHTTPClient) retryProfile(req *http.Request) RetryProfile {
+	profile := RetryProfile{MaxRetries: c.maxRetries, Backoff: c.retryBackoff}
+	if p := c.endpoints.match(req); p != nil && p.Retry != nil {
+		profile = *p.Retry
+	}
+	o := overridesFrom(req.Context())
+	if o.maxRetries != 0 {
+		profile.MaxRetries = max(o.maxRetries, 0)
+	}
+	if o.retryBackoff > 0 {
+		profile.Backoff = o.retryBackoff
+	}
+	return profile
+}
+
+// middleware serves and fills the response cache for endpoints with a TTL.
+func (e *endpointCatalog) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		p := e.match(req)
+		if p == nil || p.CacheTTL <= 0 || isStreaming(req.Context()) {
+			return next.Do(req)
+		}
+		// HEAD is answered from the GET entry
+		key := e.cache.key(http.MethodGet, req)
+		if req.Method == http.MethodHead {
+			// Metadata of a cached GET answers HEAD without a round trip
+			if resp, ok := e.cache.get(key, req); ok {
+				resp.Body = http.NoBody
+				return resp, nil
+			}
+			return next.Do(req)
+		}
+		if req.Method != http.MethodGet {
+			return next.Do(req)
+		}
+		if resp, ok := e.cache.get(key, req); ok {
+			return resp, nil
+		}
+		resp, err := next.Do(req)
+		if err != nil || resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") || !cacheable(resp) {
+			return resp, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		e.cache.put(key, req, resp, body, p.CacheTTL)
+		return resp, nil
+	})
+}
+
+// credentialHeaders always partition cached responses, so one caller is
+// never served a response fetched with another caller's credentials.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// responseCache is an in-memory cache of responses, keyed by method, URL and
+// caller.
+type responseCache struct {
+	// keyHeaders are request headers partitioning entries besides
+	// credentialHeaders.
+	keyHeaders []string
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	// vary holds the request values of the headers named by the Vary
+	// response header.
+	vary    http.Header
+	expires time.Time
+}
+
+func newResponseCache(keyHeaders []string) *responseCache {
+	return &responseCache{keyHeaders: keyHeaders, entries: make(map[string]cachedResponse)}
+}
+
+// urlKey is the key prefix shared by every caller's entry for method and
+// rawURL.
+func urlKey(method, rawURL string) string {
+	return method + " " + rawURL + "\x00"
+}
+
+// key returns the cache key of req as a method request: its URL followed by
+// a digest of its tenant, credentials and key headers.
+func (rc *responseCache) key(method string, req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, TenantFromContext(req.Context()))
+	for _, names := range [][]string{credentialHeaders, rc.keyHeaders} {
+		for _, name := range names {
+			for _, v := range req.Header.Values(name) {
+				io.WriteString(h, "\n"+http.CanonicalHeaderKey(name)+": "+v)
+			}
+		}
+	}
+	return urlKey(method, req.URL.String()) + hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable reports whether resp may be stored; Vary: * rules it out.
+func cacheable(resp *http.Response) bool {
+	for _, v := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if strings.TrimSpace(name) == "*" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// varyValues returns the values req sends for the headers resp varies on.
+func varyValues(req *http.Request, resp *http.Response) http.Header {
+	var vary http.Header
+	for _, v := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(http.Header)
+			}
+			vary[http.CanonicalHeaderKey(name)] = req.Header.Values(name)
+		}
+	}
+	return vary
+}
+
+// varyMatches reports whether req sends the header values entry was stored
+// for.
+func (entry cachedResponse) varyMatches(req *http.Request) bool {
+	for name, values := range entry.vary {
+		if !slices.Equal(req.Header.Values(name), values) {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns a fresh copy of the cached response for key.
+func (rc *responseCache) get(key string, req *http.Request) (*http.Response, bool) {
+	rc.mu.Lock()
+	entry, ok := rc.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(rc.entries, key)
+		ok = false
+	}
+	rc.mu.Unlock()
+	if !ok || !entry.varyMatches(req) {
+		return nil, false
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(entry.status) + " " + http.StatusText(entry.status),
+		StatusCode:    entry.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       req,
+	}, true
+}
+
+// deleteURL drops every caller's entry for method and rawURL.
+func (rc *responseCache) deleteURL(method, rawURL string) {
+	prefix := urlKey(method, rawURL)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for key := range rc.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+func (rc *responseCache) clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	clear(rc.entries)
+}
+
+func (rc *responseCache) put(key string, req *http.Request, resp *http.Response, body []byte, ttl time.Duration) {
+	now := time.Now()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for k, e := range rc.entries {
+		if now.After(e.expires) {
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = cachedResponse{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		vary:    varyValues(req, resp),
+		expires: now.Add(ttl),
+	}
+}
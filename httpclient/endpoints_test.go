@@ -0,0 +1,115 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+// cacheCall is one request of a response cache test.
+type cacheCall struct {
+	tenant  string
+	headers map[string]string
+}
+
+func (cc cacheCall) do(t *testing.T, c *CommonHTTPClient, path string) {
+	t.Helper()
+	ctx := context.Background()
+	if cc.tenant != "" {
+		ctx = WithTenant(ctx, cc.tenant)
+	}
+	resp, err := c.Do(ctx, RequestOptions{Method: http.MethodGet, Path: path, Headers: cc.headers})
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		vary        string
+		first, next cacheCall
+		wantCalls   int
+	}{
+		{
+			name:      "same credentials share",
+			first:     cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			next:      cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			wantCalls: 1,
+		},
+		{
+			name:      "other Authorization",
+			first:     cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			next:      cacheCall{headers: map[string]string{"Authorization": "Bearer b"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "other cookie",
+			first:     cacheCall{headers: map[string]string{"Cookie": "session=a"}},
+			next:      cacheCall{headers: map[string]string{"Cookie": "session=b"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "other tenant",
+			first:     cacheCall{tenant: "acme"},
+			next:      cacheCall{tenant: "globex"},
+			wantCalls: 2,
+		},
+		{
+			name:      "other key header",
+			first:     cacheCall{headers: map[string]string{"X-Api-Key": "a"}},
+			next:      cacheCall{headers: map[string]string{"X-Api-Key": "b"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "other varied header",
+			vary:      "Accept-Language",
+			first:     cacheCall{headers: map[string]string{"Accept-Language": "en"}},
+			next:      cacheCall{headers: map[string]string{"Accept-Language": "de"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "same varied header",
+			vary:      "Accept-Language",
+			first:     cacheCall{headers: map[string]string{"Accept-Language": "en"}},
+			next:      cacheCall{headers: map[string]string{"Accept-Language": "en"}},
+			wantCalls: 1,
+		},
+		{
+			name:      "vary star",
+			vary:      "*",
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			stub := mock.On(http.MethodGet, "/items").Reply(http.StatusOK, `{"items":[]}`)
+			if tt.vary != "" {
+				stub.Header("Vary", tt.vary)
+			}
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:         base,
+				Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient:      &http.Client{Transport: mock},
+				Endpoints:       []EndpointPolicy{{Pattern: "/items", CacheTTL: time.Minute}},
+				CacheKeyHeaders: []string{"X-Api-Key"},
+			})
+
+			tt.first.do(t, c, "/items")
+			tt.next.do(t, c, "/items")
+			if got := len(mock.Calls()); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestErrorURLsRedacted(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		cfg     ClientConfig
+		tracker bool
+		opts    RequestOptions
+	}{
+		{
+			name:   "APIError",
+			status: http.StatusForbidden,
+			cfg:    ClientConfig{ErrorOnNon2xx: true},
+			opts:   RequestOptions{Method: http.MethodGet},
+		},
+		{
+			name:   "ContentTypeError",
+			status: http.StatusOK,
+			opts:   RequestOptions{Method: http.MethodGet, ExpectedContentTypes: []string{"application/json"}},
+		},
+		{
+			name:   "PreconditionFailedError",
+			status: http.StatusPreconditionFailed,
+			opts:   RequestOptions{Method: http.MethodPut, IfMatch: `"v1"`},
+		},
+		{
+			name:    "ETagTracker",
+			status:  http.StatusPreconditionFailed,
+			tracker: true,
+			opts:    RequestOptions{Method: http.MethodPut, Headers: map[string]string{"If-Match": `"v1"`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On("", "/items").Reply(tt.status, "<html>").Header("Content-Type", "text/html")
+			cfg := tt.cfg
+			cfg.BaseURL, _ = url.Parse("http://api.test")
+			cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg.HTTPClient = &http.Client{Transport: mock}
+			cfg.RedactQueryParams = []string{"api_key"}
+			c := NewCommonHTTPClient(cfg)
+			if tt.tracker {
+				c.Use(NewETagTracker().Middleware())
+			}
+
+			opts := tt.opts
+			opts.Path = "/items"
+			opts.QueryParams = map[string]string{"api_key": "secret", "page": "2"}
+			_, err := c.Do(context.Background(), opts)
+			if err == nil {
+				t.Fatal("Do succeeded, want an error")
+			}
+			msg := err.Error()
+			if strings.Contains(msg, "secret") {
+				t.Errorf("error leaks the credential: %s", msg)
+			}
+			if !strings.Contains(msg, "api_key=****") || !strings.Contains(msg, "page=2") {
+				t.Errorf("error = %s, want the redacted URL", msg)
+			}
+		})
+	}
+}
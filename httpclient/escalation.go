@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogEscalation temporarily switches a host to full logging while it is
+// failing: once the share of failed requests (transport errors and 5xx) in a
+// Window reaches ErrorRate, requests to the host log headers, query, bodies
+// and every attempt's timing for Duration, regardless of the client's
+// DisableLog* settings, then revert. Redaction and the DisableLog* options of
+// individual requests still apply.
+type LogEscalation struct {
+	// ErrorRate is the failure share that triggers escalation, e.g. 0.5.
+	ErrorRate float64
+	// MinRequests avoids escalating on a handful of requests (default 10).
+	MinRequests int
+	// Window is the measurement period (default 1m).
+	Window time.Duration
+	// Duration is how long escalation lasts (default 5m).
+	Duration time.Duration
+}
+
+// escalator tracks per-host error rates.
+type escalator struct {
+	cfg    LogEscalation
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	hosts map[string]*hostErrors
+}
+
+type hostErrors struct {
+	windowStart time.Time
+	total       int
+	failed      int
+	until       time.Time
+}
+
+func newEscalator(cfg *LogEscalation, logger *slog.Logger) *escalator {
+	if cfg == nil {
+		return nil
+	}
+	e := &escalator{cfg: *cfg, logger: logger, hosts: make(map[string]*hostErrors)}
+	if e.cfg.MinRequests <= 0 {
+		e.cfg.MinRequests = 10
+	}
+	if e.cfg.Window <= 0 {
+		e.cfg.Window = time.Minute
+	}
+	if e.cfg.Duration <= 0 {
+		e.cfg.Duration = 5 * time.Minute
+	}
+	return e
+}
+
+// escalated reports whether host currently gets full logging.
+func (e *escalator) escalated(host string) bool {
+	if e == nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.hosts[host]
+	return ok && time.Now().Before(h.until)
+}
+
+// record counts one finished request to host.
+func (e *escalator) record(host string, failed bool) {
+	if e == nil {
+		return
+	}
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.hosts[host]
+	if !ok {
+		h = &hostErrors{windowStart: now}
+		e.hosts[host] = h
+	}
+	if now.Sub(h.windowStart) >= e.cfg.Window {
+		h.windowStart, h.total, h.failed = now, 0, 0
+	}
+	h.total++
+	if failed {
+		h.failed++
+	}
+
+	if now.Before(h.until) || h.total < e.cfg.MinRequests {
+		return
+	}
+	if rate := float64(h.failed) / float64(h.total); rate >= e.cfg.ErrorRate {
+		h.until = now.Add(e.cfg.Duration)
+		e.logger.Warn("Escalating request logging",
+			slog.String("host", host),
+			slog.Float64("error_rate", rate),
+			slog.Time("until", h.until),
+		)
+	}
+}
@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestEscalationKeepsRequestOptOuts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RequestOptions
+		// want lists the parts expected in the "Outgoing request" line
+		wantBody, wantHeaders, wantQuery bool
+	}{
+		{name: "client settings overridden", wantBody: true, wantHeaders: true, wantQuery: true},
+		{name: "request body opt-out", opts: RequestOptions{DisableLogBody: true}, wantHeaders: true, wantQuery: true},
+		{name: "request headers opt-out", opts: RequestOptions{DisableLogHeaders: true}, wantBody: true, wantQuery: true},
+		{name: "request query opt-out", opts: RequestOptions{DisableLogQuery: true}, wantBody: true, wantHeaders: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodPost, "/events").Reply(http.StatusOK, "")
+			var logs bytes.Buffer
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:           base,
+				Logger:            slog.New(slog.NewJSONHandler(&logs, nil)),
+				HTTPClient:        &http.Client{Transport: mock},
+				DisableLogBody:    true,
+				DisableLogHeaders: true,
+				DisableLogQuery:   true,
+				LogEscalation:     &LogEscalation{ErrorRate: 0.5, MinRequests: 1},
+			})
+			c.escalator.record("api.test", true)
+
+			opts := tt.opts
+			opts.Method, opts.Path = http.MethodPost, "/events"
+			opts.QueryParams = map[string]string{"page": "2"}
+			opts.Headers = map[string]string{"X-Trace": "abc"}
+			opts.Body = strings.NewReader(`{"event":"signup"}`)
+			resp, err := c.Do(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+
+			var line struct {
+				Body    string
+				Headers map[string][]string
+				Query   string
+			}
+			for _, raw := range strings.Split(logs.String(), "\n") {
+				if strings.Contains(raw, `"msg":"Outgoing request"`) {
+					if err := json.Unmarshal([]byte(raw), &line); err != nil {
+						t.Fatalf("log line %s: %v", raw, err)
+					}
+				}
+			}
+			if got := line.Body != ""; got != tt.wantBody {
+				t.Errorf("body logged = %v, want %v", got, tt.wantBody)
+			}
+			if got := line.Headers != nil; got != tt.wantHeaders {
+				t.Errorf("headers logged = %v, want %v", got, tt.wantHeaders)
+			}
+			if got := line.Query != ""; got != tt.wantQuery {
+				t.Errorf("query logged = %v, want %v", got, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrPreconditionFailed matches, via errors.Is, every *PreconditionFailedError.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// PreconditionFailedError is returned for 412 responses to conditional
+// requests, meaning the resource changed since its ETag was read.
+type PreconditionFailedError struct {
+	Method string
+	URL    string
+	// IfMatch is the ETag the request was conditioned on.
+	IfMatch string
+	// IfNoneMatch is set for requests conditioned on If-None-Match, e.g.
+	// "*" for create-only writes.
+	IfNoneMatch string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	if e.IfMatch == "" && e.IfNoneMatch != "" {
+		return fmt.Sprintf("%s %s: precondition failed (If-None-Match %s)", e.Method, e.URL, e.IfNoneMatch)
+	}
+	return fmt.Sprintf("%s %s: precondition failed (If-Match %s)", e.Method, e.URL, e.IfMatch)
+}
+
+// ETag returns the entity tag of resp, or "" when it has none.
+func ETag(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get("ETag")
+}
+
+// preconditionFailed returns a *PreconditionFailedError for a 412 response to
+// a conditional request, closing its body, or nil otherwise.
+func (c *CommonHTTPClient) preconditionFailed(resp *http.Response) error {
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Request == nil {
+		return nil
+	}
+	req := resp.Request
+	ifMatch, ifNoneMatch := req.Header.Get("If-Match"), req.Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return nil
+	}
+	resp.Body.Close()
+	return &PreconditionFailedError{Method: req.Method, URL: c.redactor.URL(req.URL), IfMatch: ifMatch, IfNoneMatch: ifNoneMatch}
+}
+
+// Is reports whether target is ErrPreconditionFailed.
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// ETagTracker remembers the ETag of every GET response by URL and sends it as
+// If-Match on later PUT, PATCH and DELETE requests to the same URL, turning
+// 412 responses into *PreconditionFailedError. Install it with
+// client.Use(tracker.Middleware()).
+type ETagTracker struct {
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+// NewETagTracker creates an empty ETagTracker.
+func NewETagTracker() *ETagTracker {
+	return &ETagTracker{etags: make(map[string]string)}
+}
+
+// ETag returns the last ETag seen for url.
+func (t *ETagTracker) ETag(url string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	etag, ok := t.etags[url]
+	return etag, ok
+}
+
+// Forget drops the ETag stored for url.
+func (t *ETagTracker) Forget(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.etags, url)
+}
+
+// Middleware returns the tracking middleware.
+func (t *ETagTracker) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			key := req.URL.String()
+			conditional := req.Method == http.MethodPut || req.Method == http.MethodPatch || req.Method == http.MethodDelete
+			if conditional && req.Header.Get("If-Match") == "" {
+				if etag, ok := t.ETag(key); ok {
+					req.Header.Set("If-Match", etag)
+				}
+			}
+
+			resp, err := next.Do(req)
+			if err != nil {
+				return nil, err
+			}
+
+			// A 412 is left to Do, which turns it into a *PreconditionFailedError
+			// with the URL redacted
+			if resp.StatusCode < 300 && (req.Method == http.MethodGet || conditional) {
+				// Successful writes usually return the new ETag; deletes clear it
+				t.mu.Lock()
+				if etag := resp.Header.Get("ETag"); etag != "" && req.Method != http.MethodDelete {
+					t.etags[key] = etag
+				} else if conditional {
+					delete(t.etags, key)
+				}
+				t.mu.Unlock()
+			}
+			return resp, nil
+		})
+	}
+}
@@ -13,7 +13,7 @@ import (
 )
 
 func Example() {
-	logger := slog.New(utils.NewPrettyJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	logger := slog.New(utils.NewPrettyJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	baseURL, _ := url.Parse("https://jsonplaceholder.typicode.com")
 	client := NewCommonHTTPClient(ClientConfig{
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// FallbackFunc supplies a substitute response when a request cannot be served:
+// its host breaker is open, or it failed after all retries. cause is the error
+// or, for a final 5xx or 429 response, an *APIError describing it. Returning an
+// error fails the request with that error instead; returning neither fails it
+// with cause.
+type FallbackFunc func(ctx context.Context, req *http.Request, cause error) (*http.Response, error)
+
+type degradedKey struct{}
+
+// Degraded reports whether resp was produced by a FallbackFunc.
+func Degraded(resp *http.Response) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+	degraded, _ := resp.Request.Context().Value(degradedKey{}).(bool)
+	return degraded
+}
+
+// StaticFallback returns a FallbackFunc serving a fixed payload, e.g. an empty
+// list or a default configuration.
+func StaticFallback(status int, contentType string, body []byte) FallbackFunc {
+	return func(ctx context.Context, req *http.Request, cause error) (*http.Response, error) {
+		header := make(http.Header)
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{
+			Status:        http.StatusText(status),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+}
+
+// fallbackFor returns the fallback of the request options, or of the
+// endpoint policy matching req.
+func (c *CommonHTTPClient) fallbackFor(req *http.Request, opts RequestOptions) FallbackFunc {
+	if opts.Fallback != nil {
+		return opts.Fallback
+	}
+	if p := c.endpoints.match(req); p != nil {
+		return p.Fallback
+	}
+	return nil
+}
+
+// degrade replaces a failed outcome with the fallback response when the
+// failure qualifies. Responses it replaces are closed.
+func (c *CommonHTTPClient) degrade(req *http.Request, opts RequestOptions, resp *http.Response, err error) (*http.Response, error) {
+	fallback := c.fallbackFor(req, opts)
+	if fallback == nil {
+		return resp, err
+	}
+
+	var cause error
+	var reqErr *RequestError
+	switch {
+	case errors.Is(err, ErrCircuitOpen), errors.As(err, &reqErr):
+		cause = err
+	case err == nil && (resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests):
+		cause = c.newAPIError(resp)
+	default:
+		return resp, err
+	}
+
+	fbResp, fbErr := fallback(req.Context(), req, cause)
+	if fbErr != nil {
+		return nil, fbErr
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if fbResp == nil {
+		return nil, cause
+	}
+	if fbResp.Request == nil {
+		fbResp.Request = req
+	}
+	fbResp.Request = fbResp.Request.WithContext(context.WithValue(fbResp.Request.Context(), degradedKey{}, true))
+	c.logEvent(req.Context(), slog.LevelWarn, "Serving fallback response", c.newLogEvent(req, 0, 0), slog.Any("cause", cause))
+	return fbResp, nil
+}
@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDependencyFailed is recorded for graph nodes skipped because a node they
+// depend on failed.
+var ErrDependencyFailed = errors.New("httpclient: dependency failed")
+
+// Node is one request in a dependency graph run by RunGraph.
+type Node struct {
+	Name      string
+	DependsOn []string
+	// Build returns the request, typically using the results of DependsOn
+	// to fill in the path, query or body.
+	Build func(ctx context.Context, deps NodeResults) (RequestOptions, error)
+	// Parse optionally decodes the response into NodeResult.Value.
+	Parse func(result *NodeResult) (any, error)
+	// Retries is how often the node is re-run after an error, on top of the
+	// client's own retries, waiting RetryBackoff in between.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// NodeResult is the outcome of a successful node.
+type NodeResult struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Value is what Parse returned.
+	Value any
+}
+
+// NodeResults maps node names to their results.
+type NodeResults map[string]*NodeResult
+
+// GraphError aggregates the failures of a graph run by node name; skipped
+// dependents carry ErrDependencyFailed.
+type GraphError struct {
+	Errors map[string]error
+}
+
+func (e *GraphError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	return fmt.Sprintf("%d graph nodes failed: %s", len(names), strings.Join(parts, "; "))
+}
+
+func (e *GraphError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// RunGraph runs the nodes with c (the default client when nil), starting each
+// as soon as its dependencies have succeeded, so independent branches run
+// concurrently under the shared ctx. Failures do not stop unrelated branches;
+// RunGraph returns the results of every successful node and a *GraphError if
+// any node failed or was skipped. Unknown dependencies and cycles are
+// reported before anything is sent.
+func RunGraph(ctx context.Context, c *CommonHTTPClient, nodes []Node) (NodeResults, error) {
+	if c == nil {
+		c = DefaultClient()
+	}
+	if err := validateGraph(nodes); err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]chan struct{}, len(nodes))
+	for _, n := range nodes {
+		done[n.Name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	results := make(NodeResults, len(nodes))
+	failures := make(map[string]error)
+
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n Node) {
+			defer wg.Done()
+			defer close(done[n.Name])
+
+			deps := make(NodeResults, len(n.DependsOn))
+			for _, dep := range n.DependsOn {
+				<-done[dep]
+				mu.Lock()
+				result, ok := results[dep]
+				mu.Unlock()
+				if !ok {
+					mu.Lock()
+					failures[n.Name] = fmt.Errorf("%w: %s", ErrDependencyFailed, dep)
+					mu.Unlock()
+					return
+				}
+				deps[dep] = result
+			}
+
+			result, err := c.runNode(ctx, n, deps)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures[n.Name] = err
+				return
+			}
+			results[n.Name] = result
+		}(n)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return results, &GraphError{Errors: failures}
+	}
+	return results, nil
+}
+
+// runNode sends one node, retrying it as configured.
+func (c *CommonHTTPClient) runNode(ctx context.Context, n Node, deps NodeResults) (*NodeResult, error) {
+	var lastErr error
+	for attempt := 0; attempt <= n.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(n.RetryBackoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		var result *NodeResult
+		if result, lastErr = c.sendNode(ctx, n, deps); lastErr == nil {
+			return result, nil
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *CommonHTTPClient) sendNode(ctx context.Context, n Node, deps NodeResults) (*NodeResult, error) {
+	opts, err := n.Build(ctx, deps)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, c.newAPIError(resp)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NodeResult{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	if n.Parse != nil {
+		if result.Value, err = n.Parse(result); err != nil {
+			return nil, fmt.Errorf("parse: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// validateGraph rejects duplicate names, unknown dependencies and cycles.
+func validateGraph(nodes []Node) error {
+	byName := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		if _, dup := byName[n.Name]; dup {
+			return fmt.Errorf("graph: duplicate node %q", n.Name)
+		}
+		byName[n.Name] = n
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(nodes))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("graph: cycle through node %q", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("graph: node %q depends on unknown node %q", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, n := range nodes {
+		if err := visit(n.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"httpclient/utils"
+)
+
+// HARConfig configures a HARRecorder.
+type HARConfig struct {
+	// MaxEntries bounds the recorded exchanges, dropping the oldest first
+	// (default 1000).
+	MaxEntries int
+	// Redactor masks headers, query parameters and JSON body fields before
+	// they are recorded (default masks utils.DefaultRedactHeaders).
+	Redactor *utils.Redactor
+	// OmitBodies records exchanges without request and response bodies.
+	OmitBodies bool
+}
+
+// HARRecorder records requests made through its Middleware as a HAR 1.2
+// archive, which browser devtools and many HTTP tools can load:
+//
+//	rec := httpclient.NewHARRecorder(httpclient.HARConfig{})
+//	client.Use(rec.Middleware)
+//	...
+//	rec.Save("session.har")
+//
+// Streamed response bodies are not recorded, as the caller consumes them.
+type HARRecorder struct {
+	cfg     HARConfig
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewHARRecorder creates a recorder applying cfg.
+func NewHARRecorder(cfg HARConfig) *HARRecorder {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.Redactor == nil {
+		cfg.Redactor = defaultRedactor
+	}
+	return &HARRecorder{cfg: cfg}
+}
+
+// Middleware records every exchange passing through it, including failed
+// ones, which carry the error in the entry's "_error" field.
+func (r *HARRecorder) Middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		var reqBody []byte
+		if !r.cfg.OmitBodies {
+			var err error
+			if reqBody, err = peekRequestBody(req); err != nil {
+				return nil, err
+			}
+		}
+		start := time.Now()
+		resp, err := next.Do(req)
+		entry := r.entry(req, reqBody, start, time.Since(start))
+		if err != nil {
+			entry.Error = err.Error()
+			entry.Response = harResponse{HTTPVersion: "HTTP/1.1", Headers: []harNameValue{}, Cookies: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+			r.add(entry)
+			return nil, err
+		}
+		if entry.Response, err = r.response(req, resp); err != nil {
+			return nil, err
+		}
+		if meta := Metadata(resp); meta != nil {
+			entry.Timings = harTimingsFrom(meta.Timings, entry.Time)
+			if host, _, err := net.SplitHostPort(meta.RemoteAddr); err == nil {
+				entry.ServerIPAddress = host
+			}
+		}
+		r.add(entry)
+		return resp, nil
+	})
+}
+
+// Entries returns the number of recorded exchanges.
+func (r *HARRecorder) Entries() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Reset drops all recorded exchanges.
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// WriteTo writes the archive as JSON to w.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	entries := append([]harEntry{}, r.entries...)
+	r.mu.Unlock()
+
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "httpclient", Version: "1"},
+		Entries: entries,
+	}}
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Save writes the archive to a .har file at path.
+func (r *HARRecorder) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *HARRecorder) add(e harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) >= r.cfg.MaxEntries {
+		r.entries = r.entries[1:]
+	}
+	r.entries = append(r.entries, e)
+}
+
+// entry records the request side of an exchange.
+func (r *HARRecorder) entry(req *http.Request, body []byte, start time.Time, d time.Duration) harEntry {
+	redactor := r.cfg.Redactor
+	u := *req.URL
+	u.RawQuery = redactor.Query(u.RawQuery)
+	request := harRequest{
+		Method:      req.Method,
+		URL:         redactor.URL(req.URL),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(redactor.Headers(req.Header)),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if req.Proto != "" {
+		request.HTTPVersion = req.Proto
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			request.QueryString = append(request.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+	if len(body) > 0 {
+		request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     redactor.Body(string(body)),
+		}
+	}
+	return harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            durationMillis(d),
+		Request:         request,
+		Cache:           struct{}{},
+		Timings:         harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: durationMillis(d), Receive: 0},
+	}
+}
+
+// response records the response side of an exchange, buffering and
+// replacing its body unless it is streamed.
+func (r *HARRecorder) response(req *http.Request, resp *http.Response) (harResponse, error) {
+	out := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(r.cfg.Redactor.Headers(resp.Header)),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    -1,
+		Content:     harContent{Size: -1, MimeType: resp.Header.Get("Content-Type")},
+	}
+	if out.HTTPVersion == "" {
+		out.HTTPVersion = "HTTP/1.1"
+	}
+	if r.cfg.OmitBodies || resp.Body == nil || isStreaming(req.Context()) {
+		return out, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return out, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	out.BodySize, out.Content.Size = len(body), len(body)
+	if utf8.Valid(body) {
+		out.Content.Text = r.cfg.Redactor.Body(string(body))
+	} else {
+		out.Content.Text, out.Content.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return out, nil
+}
+
+// harTimingsFrom maps the client's phase timings onto HAR timings; the
+// remainder of total counts as send time.
+func harTimingsFrom(t Timings, total float64) harTimings {
+	out := harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	if !t.ConnReused {
+		out.DNS, out.Connect = durationMillis(t.DNS), durationMillis(t.Connect+t.TLS)
+		if t.TLS > 0 {
+			out.SSL = durationMillis(t.TLS)
+		}
+	}
+	out.Wait, out.Receive = durationMillis(t.TTFB), durationMillis(t.Transfer)
+	out.Send = max(total-max(out.DNS, 0)-max(out.Connect, 0)-out.Wait-out.Receive, 0)
+	return out
+}
+
+func harHeaders(h map[string][]string) []harNameValue {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	out := []harNameValue{}
+	for _, name := range names {
+		for _, v := range h[name] {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
@@ -0,0 +1,89 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ResourceInfo is the metadata of a resource as reported by its response
+// headers, without the body.
+type ResourceInfo struct {
+	// Exists is false when the server answered 404 or 410.
+	Exists     bool
+	StatusCode int
+	// Size is the full length of the resource in bytes, or -1 when unknown.
+	// For 206 responses it is taken from Content-Range.
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	ContentType  string
+	// AcceptRanges is "bytes" when range requests are supported.
+	AcceptRanges string
+	Header       http.Header
+}
+
+// ParseResourceInfo extracts ResourceInfo from the headers of resp. The body
+// is not read.
+func ParseResourceInfo(resp *http.Response) *ResourceInfo {
+	h := resp.Header
+	info := &ResourceInfo{
+		Exists:       resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone,
+		StatusCode:   resp.StatusCode,
+		Size:         -1,
+		ETag:         h.Get("ETag"),
+		ContentType:  h.Get("Content-Type"),
+		AcceptRanges: h.Get("Accept-Ranges"),
+		Header:       h,
+	}
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		info.LastModified = t
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		if cr, err := ParseContentRange(h.Get("Content-Range")); err == nil && cr.Size >= 0 {
+			info.Size = cr.Size
+		}
+		return info
+	}
+	if resp.ContentLength >= 0 {
+		info.Size = resp.ContentLength
+	} else if n, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		// HEAD responses carry the length of the body a GET would return
+		info.Size = n
+	}
+	return info
+}
+
+// Head issues a HEAD request for opts and returns the parsed metadata. A 404
+// or 410 is reported as a ResourceInfo with Exists false rather than an
+// error; other non-2xx responses return an *APIError.
+func (c *CommonHTTPClient) Head(ctx context.Context, opts RequestOptions) (*ResourceInfo, error) {
+	opts.Method = http.MethodHead
+	resp, err := c.Do(ctx, opts)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone) {
+		// ErrorOnNon2xx turned the miss into an error
+		return &ResourceInfo{StatusCode: apiErr.StatusCode, Size: -1, Header: apiErr.Header}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	info := ParseResourceInfo(resp)
+	if info.Exists && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return nil, c.newAPIError(resp)
+	}
+	return info, nil
+}
+
+// Exists reports whether path exists on the server using a HEAD request.
+func (c *CommonHTTPClient) Exists(ctx context.Context, path string) (bool, error) {
+	info, err := c.Head(ctx, RequestOptions{Path: path})
+	if err != nil {
+		return false, err
+	}
+	return info.Exists, nil
+}
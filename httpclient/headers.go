@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// HeaderValidation selects how invalid header names and values are handled.
+type HeaderValidation int
+
+const (
+	// HeaderReject fails the request with a *HeaderError (the default).
+	HeaderReject HeaderValidation = iota
+	// HeaderSanitize strips control characters from values and drops headers
+	// with invalid names, logging a warning for each change.
+	HeaderSanitize
+)
+
+// HeaderError reports an invalid header name or value, e.g. a value carrying
+// a CRLF sequence from untrusted input.
+type HeaderError struct {
+	Name   string
+	Reason string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("invalid header %q: %s", e.Name, e.Reason)
+}
+
+// setHeaders validates and sets headers on h according to the client policy.
+func (c *CommonHTTPClient) setHeaders(h http.Header, headers map[string]string) error {
+	for k, v := range headers {
+		v, ok, err := c.checkHeader(k, v)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.Set(k, v)
+		}
+	}
+	return nil
+}
+
+// setHeaderValues validates multi-valued headers and applies them to h. With
+// add unset, each key replaces the values set so far and a key without values
+// removes the header; otherwise the values are appended.
+func (c *CommonHTTPClient) setHeaderValues(h http.Header, headers http.Header, add bool) error {
+	for k, values := range headers {
+		if !add {
+			h.Del(k)
+		}
+		for _, v := range values {
+			v, ok, err := c.checkHeader(k, v)
+			if err != nil {
+				return err
+			}
+			if ok {
+				h.Add(k, v)
+			}
+		}
+	}
+	return nil
+}
+
+// checkHeader validates a header according to the client policy, returning
+// the value to set, or false when the header is dropped.
+func (c *CommonHTTPClient) checkHeader(k, v string) (string, bool, error) {
+	if !httpguts.ValidHeaderFieldName(k) {
+		if c.headerValidation == HeaderSanitize {
+			c.logger.Warn("Dropping header with invalid name", slog.String("header", k))
+			return "", false, nil
+		}
+		return "", false, &HeaderError{Name: k, Reason: "invalid name"}
+	}
+	if !httpguts.ValidHeaderFieldValue(v) {
+		if c.headerValidation == HeaderSanitize {
+			c.logger.Warn("Sanitizing header value", slog.String("header", k))
+			return sanitizeHeaderValue(v), true, nil
+		}
+		return "", false, &HeaderError{Name: k, Reason: "value contains control characters"}
+	}
+	return v, true, nil
+}
+
+// sanitizeHeaderValue replaces control characters (including CR and LF) with
+// spaces, keeping horizontal tabs, and trims the result.
+func sanitizeHeaderValue(v string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == '\t' || (r >= 0x20 && r != 0x7f) {
+			return r
+		}
+		return ' '
+	}, v))
+}
@@ -0,0 +1,283 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Dependency describes an upstream endpoint probed by a HealthChecker.
+type Dependency struct {
+	Name    string
+	Client  *CommonHTTPClient
+	Request RequestOptions
+	// Timeout bounds a single probe (default 5s).
+	Timeout time.Duration
+	// Optional dependencies are reported but do not fail the aggregate status.
+	Optional bool
+}
+
+// DependencyStatus is the outcome of the latest probe of one dependency.
+type DependencyStatus struct {
+	Name       string        `json:"name"`
+	Healthy    bool          `json:"healthy"`
+	Optional   bool          `json:"optional,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency_ns"`
+	Error      string        `json:"error,omitempty"`
+	CheckedAt  time.Time     `json:"checked_at"`
+}
+
+// HealthStatus aggregates the status of all dependencies.
+type HealthStatus struct {
+	Healthy      bool               `json:"healthy"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// HealthChecker probes dependencies on demand or periodically and exposes the
+// aggregate status, e.g. behind a /readyz handler.
+type HealthChecker struct {
+	deps []Dependency
+
+	mu   sync.RWMutex
+	last HealthStatus
+}
+
+// NewHealthChecker creates a HealthChecker for the given dependencies.
+func NewHealthChecker(deps ...Dependency) *HealthChecker {
+	return &HealthChecker{deps: deps}
+}
+
+// Check probes every dependency concurrently and stores the result.
+func (h *HealthChecker) Check(ctx context.Context) HealthStatus {
+	statuses := make([]DependencyStatus, len(h.deps))
+	var wg sync.WaitGroup
+	for i, dep := range h.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = probeDependency(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	status := HealthStatus{Healthy: true, Dependencies: statuses}
+	for _, s := range statuses {
+		if !s.Healthy && !s.Optional {
+			status.Healthy = false
+		}
+	}
+
+	h.mu.Lock()
+	h.last = status
+	h.mu.Unlock()
+	return status
+}
+
+// Run checks immediately and then every interval until ctx is done.
+func (h *HealthChecker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the result of the most recent Check.
+func (h *HealthChecker) Status() HealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.last
+}
+
+// ServeHTTP writes the last status as JSON, with 503 when unhealthy. Add
+// "?fresh=1" to probe on demand instead of reporting the cached status.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := h.Status()
+	if r.URL.Query().Get("fresh") != "" || status.Dependencies == nil {
+		status = h.Check(r.Context())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+func probeDependency(ctx context.Context, dep Dependency) DependencyStatus {
+	timeout := dep.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	opts := dep.Request
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+
+	status := DependencyStatus{Name: dep.Name, Optional: dep.Optional, CheckedAt: time.Now()}
+	resp, err := dep.Client.Do(ctx, opts)
+	status.Latency = time.Since(status.CheckedAt)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	resp.Body.Close()
+
+	status.StatusCode = resp.StatusCode
+	status.Healthy = resp.StatusCode < 400
+	if !status.Healthy {
+		status.Error = fmt.Sprintf("unhealthy status %d", resp.StatusCode)
+	}
+	return status
+}
+
+// HealthProbe configures the client's own periodic probe; see RunHealthProbe.
+type HealthProbe struct {
+	// Path is probed with GET, e.g. "/healthz".
+	Path string
+	// Interval between probes (default 10s); Timeout bounds each (default 5s).
+	Interval time.Duration
+	Timeout  time.Duration
+	// FailureThreshold and SuccessThreshold are the consecutive failed or
+	// successful probes needed to switch state (default 1 each).
+	FailureThreshold int
+	SuccessThreshold int
+	// OnChange is called on every transition between healthy and unhealthy.
+	OnChange func(healthy bool, status DependencyStatus)
+}
+
+// HealthTransition records one change of the probed health.
+type HealthTransition struct {
+	Time    time.Time        `json:"time"`
+	Healthy bool             `json:"healthy"`
+	Status  DependencyStatus `json:"status"`
+}
+
+// healthTransitionLimit is how many transitions a client remembers.
+const healthTransitionLimit = 20
+
+// healthState tracks the results of RunHealthProbe.
+type healthState struct {
+	mu          sync.Mutex
+	unhealthy   bool
+	failures    int
+	successes   int
+	transitions []HealthTransition
+}
+
+// HealthCheck probes path on the client's base URL with a single GET without
+// retries; statuses below 400 are healthy. It does not change Healthy.
+func (c *CommonHTTPClient) HealthCheck(ctx context.Context, path string) DependencyStatus {
+	timeout := 5 * time.Second
+	if c.healthProbe != nil && c.healthProbe.Timeout > 0 {
+		timeout = c.healthProbe.Timeout
+	}
+	noRetry := RetryPolicyFunc(func(*http.Response, error, int) (bool, time.Duration) {
+		return false, 0
+	})
+	return probeDependency(ctx, Dependency{
+		Name:    path,
+		Client:  c,
+		Request: RequestOptions{Method: http.MethodGet, Path: path, RetryPolicy: noRetry},
+		Timeout: timeout,
+	})
+}
+
+// RunHealthProbe probes ClientConfig.HealthProbe immediately and then every
+// interval until ctx is done, updating Healthy, e.g. to stop sending traffic
+// while the backend deploys. Call it in its own goroutine; it returns at once
+// when no probe is configured.
+func (c *CommonHTTPClient) RunHealthProbe(ctx context.Context) {
+	probe := c.healthProbe
+	if probe == nil {
+		return
+	}
+	interval := probe.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.recordHealth(c.HealthCheck(ctx, probe.Path))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy reports the state determined by RunHealthProbe. Clients start out
+// healthy, and stay so without a probe.
+func (c *CommonHTTPClient) Healthy() bool {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return !c.health.unhealthy
+}
+
+// HealthTransitions returns the recent health changes, oldest first.
+func (c *CommonHTTPClient) HealthTransitions() []HealthTransition {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return append([]HealthTransition(nil), c.health.transitions...)
+}
+
+// recordHealth applies one probe result and reports transitions.
+func (c *CommonHTTPClient) recordHealth(status DependencyStatus) {
+	probe := c.healthProbe
+	h := c.health
+	h.mu.Lock()
+	if status.Healthy {
+		h.successes, h.failures = h.successes+1, 0
+	} else {
+		h.failures, h.successes = h.failures+1, 0
+	}
+	changed := false
+	switch {
+	case h.unhealthy && h.successes >= max(probe.SuccessThreshold, 1):
+		h.unhealthy, changed = false, true
+	case !h.unhealthy && h.failures >= max(probe.FailureThreshold, 1):
+		h.unhealthy, changed = true, true
+	}
+	healthy := !h.unhealthy
+	if changed {
+		h.transitions = append(h.transitions, HealthTransition{Time: status.CheckedAt, Healthy: healthy, Status: status})
+		if len(h.transitions) > healthTransitionLimit {
+			h.transitions = h.transitions[len(h.transitions)-healthTransitionLimit:]
+		}
+	}
+	h.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	attrs := []any{
+		slog.String("path", probe.Path),
+		slog.Int("status_code", status.StatusCode),
+	}
+	if status.Error != "" {
+		attrs = append(attrs, slog.String("error", status.Error))
+	}
+	if healthy {
+		c.logger.Info("Health probe recovered", attrs...)
+	} else {
+		c.logger.Warn("Health probe failing", attrs...)
+	}
+	if probe.OnChange != nil {
+		probe.OnChange(healthy, status)
+	}
+}
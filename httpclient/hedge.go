@@ -0,0 +1,117 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HedgeConfig enables hedged requests: when an attempt has not produced a
+// response after Delay, a duplicate is sent and whichever responds first is
+// used; the others are cancelled. Requests opt in with RequestOptions.Hedge.
+type HedgeConfig struct {
+	Delay time.Duration
+	// MaxHedges is the number of duplicates sent per attempt (default 1).
+	MaxHedges int
+}
+
+type hedgeKey struct{}
+
+// withHedging marks a request context as opted in to hedging.
+func withHedging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hedgeKey{}, true)
+}
+
+func isHedged(ctx context.Context) bool {
+	hedged, _ := ctx.Value(hedgeKey{}).(bool)
+	return hedged
+}
+
+type hedgeResult struct {
+	resp  *http.Response
+	err   error
+	index int
+}
+
+// roundTrip sends one attempt of req, hedging it when enabled for the request
+// and its body can be replayed.
+func (c *CommonHTTPClient) roundTrip(req *http.Request, sent *atomic.Int64) (*http.Response, error) {
+	client, err := c.clientFor(req)
+	if err != nil {
+		return nil, err
+	}
+	h := c.hedging
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if h == nil || !isHedged(req.Context()) || !replayable {
+		return client.Do(req)
+	}
+	maxHedges := h.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+
+	results := make(chan hedgeResult, maxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func(r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := client.Do(r.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, index: index}
+		}()
+	}
+	launch(req)
+	inFlight := 1
+
+	timer := time.NewTimer(h.Delay)
+	defer timer.Stop()
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case <-timer.C:
+			dup := req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					continue
+				}
+				dup.Body = &countingReadCloser{ReadCloser: body, n: sent}
+			}
+			launch(dup)
+			inFlight++
+			if len(cancels) <= maxHedges {
+				timer.Reset(h.Delay)
+			}
+		case r := <-results:
+			inFlight--
+			if r.err != nil {
+				cancels[r.index]()
+				lastErr = r.err
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != r.index {
+					cancel()
+				}
+			}
+			// The winner keeps its context until the body is closed
+			r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: cancels[r.index]}
+			go drainHedges(results, inFlight)
+			return r.resp, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// drainHedges releases the responses of the n cancelled requests still in
+// flight.
+func drainHedges(results <-chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		r := <-results
+		if r.resp != nil {
+			r.resp.Body.Close()
+		}
+	}
+}
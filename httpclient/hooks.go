@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// Hooks observe and adjust requests at points of their lifecycle. Any hook
+// may be nil. Client hooks (ClientConfig.Hooks) run before request hooks
+// (RequestOptions.Hooks).
+type Hooks struct {
+	// OnRequest runs before every attempt is sent and may modify it, e.g. to
+	// set a fresh token. An error aborts the request; the returned
+	// *RequestError wraps it. Changes are not covered by ClientConfig.Signer,
+	// which signs earlier.
+	OnRequest func(req *http.Request) error
+	// OnResponse runs once with the final response, after retries.
+	OnResponse func(resp *http.Response)
+	// OnError runs once when the request fails without a response.
+	OnError func(req *http.Request, err error)
+	// OnRetry runs before waiting to retry. attempt is the 1-based number of
+	// the attempt that failed; resp is nil when err is set.
+	OnRetry func(req *http.Request, attempt int, resp *http.Response, err error)
+}
+
+type hooksKey struct{}
+
+// withHooks returns a context carrying request hooks.
+func withHooks(ctx context.Context, h *Hooks) context.Context {
+	return context.WithValue(ctx, hooksKey{}, h)
+}
+
+// hooksFor returns the hooks that apply to req, client hooks first.
+func (c *CommonHTTPClient) hooksFor(req *http.Request) []*Hooks {
+	var hooks []*Hooks
+	if c.hooks != nil {
+		hooks = append(hooks, c.hooks)
+	}
+	if h, ok := req.Context().Value(hooksKey{}).(*Hooks); ok && h != nil {
+		hooks = append(hooks, h)
+	}
+	return hooks
+}
+
+func runRequestHooks(hooks []*Hooks, req *http.Request) error {
+	for _, h := range hooks {
+		if h.OnRequest != nil {
+			if err := h.OnRequest(req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runResponseHooks(hooks []*Hooks, resp *http.Response) {
+	for _, h := range hooks {
+		if h.OnResponse != nil {
+			h.OnResponse(resp)
+		}
+	}
+}
+
+func runErrorHooks(hooks []*Hooks, req *http.Request, err error) {
+	for _, h := range hooks {
+		if h.OnError != nil {
+			h.OnError(req, err)
+		}
+	}
+}
+
+func runRetryHooks(hooks []*Hooks, req *http.Request, attempt int, resp *http.Response, err error) {
+	for _, h := range hooks {
+		if h.OnRetry != nil {
+			h.OnRetry(req, attempt, resp, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostPolicy restricts which hosts a client may call. Patterns are exact host
+// names or "*.example.com" wildcards matching any subdomain. Deny wins over
+// Allow; when Allow is non-empty, unlisted hosts are rejected. The policy is
+// checked before every request and on every redirect hop.
+type HostPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// HostNotAllowedError is returned when a request or redirect targets a host
+// rejected by the HostPolicy.
+type HostNotAllowedError struct {
+	Host string
+}
+
+func (e *HostNotAllowedError) Error() string {
+	return fmt.Sprintf("host %q is not allowed by host policy", e.Host)
+}
+
+// Check returns a *HostNotAllowedError when host is not permitted.
+func (p *HostPolicy) Check(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.Deny {
+		if hostMatches(host, pattern) {
+			return &HostNotAllowedError{Host: host}
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, pattern := range p.Allow {
+		if hostMatches(host, pattern) {
+			return nil
+		}
+	}
+	return &HostNotAllowedError{Host: host}
+}
+
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
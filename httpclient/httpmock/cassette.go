@@ -0,0 +1,183 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+
+	"httpclient/utils"
+)
+
+// ErrNoInteraction is returned in replay mode for requests missing from the
+// cassette.
+var ErrNoInteraction = errors.New("httpmock: no recorded interaction for request")
+
+// Mode selects how a Recorder treats its cassette.
+type Mode int
+
+const (
+	// ModeReplay serves only recorded interactions and never touches the
+	// network, making tests deterministic.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the real transport and records it,
+	// replacing the cassette.
+	ModeRecord
+	// ModeReplayOrRecord replays known interactions and records new ones.
+	ModeReplayOrRecord
+)
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request part of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response part of an Interaction.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper backed by a cassette file of
+// interactions. Requests are matched by method, URL and body; repeated
+// identical requests replay their recordings in order. Credentials in
+// Authorization, Cookie and similar headers are masked before saving.
+type Recorder struct {
+	path     string
+	mode     Mode
+	next     http.RoundTripper
+	redactor *utils.Redactor
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder opens the cassette at path. next sends live requests when
+// recording (http.DefaultTransport when nil). A missing cassette is an error
+// in ModeReplay only.
+func NewRecorder(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next, redactor: utils.NewRedactor(nil, nil, nil)}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && mode == ModeReplayOrRecord:
+		return r, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	if r.mode != ModeRecord {
+		if resp, ok := r.replay(req, body); ok {
+			return resp, nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+		}
+	}
+	return r.record(req, body)
+}
+
+// replay returns the first unused interaction matching req.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Method != req.Method || in.Request.URL != req.URL.String() || in.Request.Body != string(body) {
+			continue
+		}
+		r.used[i] = true
+		return newResponse(req, in.Response.Status, in.Response.Header.Clone(), []byte(in.Response.Body)), true
+	}
+	return nil, false
+}
+
+// record sends req to the real transport and saves the interaction.
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	live := req.Clone(req.Context())
+	live.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.next.RoundTrip(live)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redactor.Headers(req.Header),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: r.redactor.Headers(resp.Header),
+			Body:   string(respBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, in)
+	r.used = append(r.used, true)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save writes the cassette through a temporary file renamed into place.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
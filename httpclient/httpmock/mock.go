@@ -0,0 +1,217 @@
+// Package httpmock provides test doubles for the HTTP clients in this module:
+// stubbed responses registered by method, path and matchers, and VCR-style
+// cassettes that record live traffic to golden files and replay it.
+//
+// Both Mock and Recorder are http.RoundTrippers, so they plug into every
+// client through its transport:
+//
+//	httpclient.ClientConfig{HTTPClient: &http.Client{Transport: mock}}
+//	httpclient2.New(httpclient2.WithTransport(mock))
+//	hwaasresty.ClientConfig{Transport: mock}
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNoStub is returned for requests matching no registered stub.
+var ErrNoStub = errors.New("httpmock: no stub for request")
+
+// Matcher reports whether a request satisfies a stub condition.
+type Matcher func(req *http.Request, body []byte) bool
+
+// MatchQuery matches requests whose query parameter key equals value.
+func MatchQuery(key, value string) Matcher {
+	return func(req *http.Request, _ []byte) bool {
+		return req.URL.Query().Get(key) == value
+	}
+}
+
+// MatchHeader matches requests whose header key equals value.
+func MatchHeader(key, value string) Matcher {
+	return func(req *http.Request, _ []byte) bool {
+		return req.Header.Get(key) == value
+	}
+}
+
+// MatchBodyContains matches requests whose body contains substr.
+func MatchBodyContains(substr string) Matcher {
+	return func(_ *http.Request, body []byte) bool {
+		return bytes.Contains(body, []byte(substr))
+	}
+}
+
+// Mock is an http.RoundTripper serving registered stubs. Stubs are tried in
+// registration order; the first whose method, path and matchers all match
+// responds.
+type Mock struct {
+	mu    sync.Mutex
+	stubs []*Stub
+	calls []*http.Request
+}
+
+// New returns an empty Mock.
+func New() *Mock {
+	return &Mock{}
+}
+
+// On registers a stub for method and URL path, e.g. On("GET", "/users/1").
+// An empty method matches any method. The stub replies 200 with an empty body
+// until configured otherwise.
+func (m *Mock) On(method, path string, matchers ...Matcher) *Stub {
+	s := &Stub{method: method, path: path, matchers: matchers, status: http.StatusOK, header: make(http.Header)}
+	m.mu.Lock()
+	m.stubs = append(m.stubs, s)
+	m.mu.Unlock()
+	return s
+}
+
+// Calls returns the requests received so far, in order.
+func (m *Mock) Calls() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request(nil), m.calls...)
+}
+
+// TB is the subset of testing.TB used by the assertion helpers.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertCalled reports every stub that was never used.
+func (m *Mock) AssertCalled(t TB) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.stubs {
+		if s.hits == 0 {
+			t.Errorf("httpmock: stub %s %s was never called", s.method, s.path)
+		}
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, req)
+	var stub *Stub
+	for _, s := range m.stubs {
+		if s.matches(req, body) {
+			stub = s
+			s.hits++
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if stub == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoStub, req.Method, req.URL)
+	}
+	return stub.respond(req)
+}
+
+// Stub is a canned response registered with Mock.On.
+type Stub struct {
+	method   string
+	path     string
+	matchers []Matcher
+	status   int
+	header   http.Header
+	body     []byte
+	err      error
+	fn       func(req *http.Request) (*http.Response, error)
+	hits     int
+}
+
+// Reply sets the status and body of the response.
+func (s *Stub) Reply(status int, body string) *Stub {
+	s.status, s.body = status, []byte(body)
+	return s
+}
+
+// ReplyJSON sets the status and a JSON-encoded body with its Content-Type.
+func (s *Stub) ReplyJSON(status int, v any) *Stub {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("httpmock: marshal reply: %v", err))
+	}
+	s.status, s.body = status, data
+	s.header.Set("Content-Type", "application/json")
+	return s
+}
+
+// Header adds a response header.
+func (s *Stub) Header(key, value string) *Stub {
+	s.header.Add(key, value)
+	return s
+}
+
+// Fail makes the stub return err instead of a response, e.g. to simulate
+// connection failures.
+func (s *Stub) Fail(err error) *Stub {
+	s.err = err
+	return s
+}
+
+// ReplyFunc computes the response for each request.
+func (s *Stub) ReplyFunc(fn func(req *http.Request) (*http.Response, error)) *Stub {
+	s.fn = fn
+	return s
+}
+
+func (s *Stub) matches(req *http.Request, body []byte) bool {
+	if s.method != "" && !strings.EqualFold(s.method, req.Method) {
+		return false
+	}
+	if s.path != req.URL.Path {
+		return false
+	}
+	for _, match := range s.matchers {
+		if !match(req, body) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Stub) respond(req *http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.fn != nil {
+		return s.fn(req)
+	}
+	return newResponse(req, s.status, s.header.Clone(), s.body), nil
+}
+
+// newResponse builds a complete response to req.
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,194 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Call is a request captured by RequestRecorder.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+func (c Call) String() string {
+	return c.Method + " " + c.URL.String()
+}
+
+// Expectation checks one aspect of a Call, returning a description of the
+// mismatch or nil.
+type Expectation func(call Call) error
+
+// BodyJSONMatches expects a JSON body semantically equal to v, ignoring key
+// order and formatting. v may be a JSON string, []byte or any marshalable value.
+func BodyJSONMatches(v any) Expectation {
+	var want any
+	var raw []byte
+	switch v := v.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			panic(fmt.Sprintf("httpmock: marshal expected body: %v", err))
+		}
+	}
+	if err := json.Unmarshal(raw, &want); err != nil {
+		panic(fmt.Sprintf("httpmock: expected body is not JSON: %v", err))
+	}
+	return func(call Call) error {
+		var got any
+		if err := json.Unmarshal(call.Body, &got); err != nil {
+			return fmt.Errorf("body is not JSON: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("body %s, want %s", call.Body, raw)
+		}
+		return nil
+	}
+}
+
+// BodyContains expects the body to contain substr.
+func BodyContains(substr string) Expectation {
+	return func(call Call) error {
+		if !bytes.Contains(call.Body, []byte(substr)) {
+			return fmt.Errorf("body %q does not contain %q", call.Body, substr)
+		}
+		return nil
+	}
+}
+
+// HeaderEquals expects header key to equal value.
+func HeaderEquals(key, value string) Expectation {
+	return func(call Call) error {
+		if got := call.Header.Get(key); got != value {
+			return fmt.Errorf("header %s = %q, want %q", key, got, value)
+		}
+		return nil
+	}
+}
+
+// QueryEquals expects query parameter key to equal value.
+func QueryEquals(key, value string) Expectation {
+	return func(call Call) error {
+		if got := call.URL.Query().Get(key); got != value {
+			return fmt.Errorf("query %s = %q, want %q", key, got, value)
+		}
+		return nil
+	}
+}
+
+// RequestRecorder is an http.RoundTripper that captures every request before
+// passing it to the next transport, typically a Mock. Use it to assert what a
+// client sent without running an httptest server.
+type RequestRecorder struct {
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewRequestRecorder wraps next (http.DefaultTransport when nil).
+func NewRequestRecorder(next http.RoundTripper) *RequestRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RequestRecorder{next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *RequestRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: req.Method, URL: &u, Header: req.Header.Clone(), Body: body})
+	r.mu.Unlock()
+	return r.next.RoundTrip(req)
+}
+
+// Calls returns the captured requests, in order.
+func (r *RequestRecorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Reset forgets all captured requests.
+func (r *RequestRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// AssertCalled reports an error unless some captured request has the method
+// and URL path and meets every expectation. On failure it lists the near
+// misses so the difference is visible in the test output.
+func (r *RequestRecorder) AssertCalled(t TB, method, path string, expectations ...Expectation) bool {
+	t.Helper()
+	var misses []string
+	for _, call := range r.Calls() {
+		if !strings.EqualFold(call.Method, method) || call.URL.Path != path {
+			continue
+		}
+		failed := false
+		for _, expect := range expectations {
+			if err := expect(call); err != nil {
+				misses = append(misses, fmt.Sprintf("  %s: %v", call, err))
+				failed = true
+				break
+			}
+		}
+		if !failed {
+			return true
+		}
+	}
+	if len(misses) == 0 {
+		t.Errorf("httpmock: expected %s %s, got %d other requests", method, path, len(r.Calls()))
+	} else {
+		t.Errorf("httpmock: no %s %s matched:\n%s", method, path, strings.Join(misses, "\n"))
+	}
+	return false
+}
+
+// AssertNotCalled reports an error if any captured request has the method and
+// URL path.
+func (r *RequestRecorder) AssertNotCalled(t TB, method, path string) bool {
+	t.Helper()
+	for _, call := range r.Calls() {
+		if strings.EqualFold(call.Method, method) && call.URL.Path == path {
+			t.Errorf("httpmock: unexpected %s", call)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertCount reports an error unless exactly n requests were captured.
+func (r *RequestRecorder) AssertCount(t TB, n int) bool {
+	t.Helper()
+	if got := len(r.Calls()); got != n {
+		t.Errorf("httpmock: got %d requests, want %d", got, n)
+		return false
+	}
+	return true
+}
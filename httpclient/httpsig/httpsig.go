@@ -0,0 +1,477 @@
+// Package httpsig implements HTTP Message Signatures (RFC 9421) for requests,
+// with Content-Digest (RFC 9530) for bodies. A Signer plugs into every client
+// in this module:
+//
+//	signer := &httpsig.Signer{KeyID: "my-key", Algorithm: httpsig.Ed25519(priv)}
+//	httpclient.ClientConfig{Signer: signer}  // or Middleware: httpclient.Signing(signer)
+//	httpclient2.New(httpclient2.WithSigner(signer))
+//	hwaasresty.ClientConfig{Signer: signer}
+//
+// Receivers check requests with Verify.
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrVerification matches, via errors.Is, every failed Verify.
+var ErrVerification = errors.New("http message signature verification failed")
+
+// Algorithm signs and verifies signature bases with one key.
+type Algorithm interface {
+	// Name is the registered algorithm name, e.g. "ed25519".
+	Name() string
+	Sign(base []byte) ([]byte, error)
+	Verify(base, sig []byte) error
+}
+
+// Ed25519 returns the "ed25519" algorithm. Pass a private key to sign; for
+// verification only, Ed25519Public suffices.
+func Ed25519(key ed25519.PrivateKey) Algorithm {
+	return ed25519Alg{priv: key, pub: key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519Public returns an "ed25519" algorithm that can only verify.
+func Ed25519Public(key ed25519.PublicKey) Algorithm {
+	return ed25519Alg{pub: key}
+}
+
+type ed25519Alg struct {
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+func (ed25519Alg) Name() string { return "ed25519" }
+
+func (a ed25519Alg) Sign(base []byte) ([]byte, error) {
+	if a.priv == nil {
+		return nil, errors.New("ed25519: no private key")
+	}
+	return ed25519.Sign(a.priv, base), nil
+}
+
+func (a ed25519Alg) Verify(base, sig []byte) error {
+	if !ed25519.Verify(a.pub, base, sig) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// ECDSAP256 returns the "ecdsa-p256-sha256" algorithm for a P-256 key.
+func ECDSAP256(key *ecdsa.PrivateKey) Algorithm {
+	return ecdsaAlg{priv: key, pub: &key.PublicKey}
+}
+
+// ECDSAP256Public returns an "ecdsa-p256-sha256" algorithm that can only verify.
+func ECDSAP256Public(key *ecdsa.PublicKey) Algorithm {
+	return ecdsaAlg{pub: key}
+}
+
+type ecdsaAlg struct {
+	priv *ecdsa.PrivateKey
+	pub  *ecdsa.PublicKey
+}
+
+func (ecdsaAlg) Name() string { return "ecdsa-p256-sha256" }
+
+// Sign returns r and s as fixed 32-byte big-endian integers, as RFC 9421
+// requires instead of ASN.1.
+func (a ecdsaAlg) Sign(base []byte) ([]byte, error) {
+	if a.priv == nil {
+		return nil, errors.New("ecdsa: no private key")
+	}
+	digest := sha256.Sum256(base)
+	r, s, err := ecdsa.Sign(rand.Reader, a.priv, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
+
+func (a ecdsaAlg) Verify(base, sig []byte) error {
+	if len(sig) != 64 {
+		return ErrVerification
+	}
+	digest := sha256.Sum256(base)
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(a.pub, digest[:], r, s) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// HMACSHA256 returns the "hmac-sha256" algorithm with a shared key.
+func HMACSHA256(key []byte) Algorithm {
+	return hmacAlg(key)
+}
+
+type hmacAlg []byte
+
+func (hmacAlg) Name() string { return "hmac-sha256" }
+
+func (a hmacAlg) Sign(base []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, a)
+	mac.Write(base)
+	return mac.Sum(nil), nil
+}
+
+func (a hmacAlg) Verify(base, sig []byte) error {
+	want, _ := a.Sign(base)
+	if !hmac.Equal(sig, want) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// DefaultComponents are covered when Signer.Components is empty;
+// "content-digest" is added for requests with a body.
+var DefaultComponents = []string{"@method", "@target-uri", "content-type"}
+
+// Signer signs requests and implements the Signer interfaces of the clients.
+type Signer struct {
+	KeyID     string
+	Algorithm Algorithm
+	// Label names the signature in Signature-Input and Signature (default
+	// "sig1").
+	Label string
+	// Components lists the covered derived components ("@method",
+	// "@target-uri", "@authority", "@scheme", "@request-target", "@path",
+	// "@query") and lowercase header names. Headers missing from a request
+	// are skipped, except "content-digest", which is computed from the body.
+	Components []string
+	// TTL, if > 0, adds an expires parameter this long after created.
+	TTL time.Duration
+	// Nonce adds a random nonce parameter for replay protection.
+	Nonce bool
+	// Tag is an optional application-specific tag parameter.
+	Tag string
+	// IncludeAlg adds the alg parameter; verifiers usually know it from the
+	// key.
+	IncludeAlg bool
+	// Clock supplies the created time (default time.Now).
+	Clock func() time.Time
+}
+
+// Sign adds Signature-Input and Signature headers to req, and Content-Digest
+// when it is covered. body is the complete request body, nil when there is
+// none.
+func (s *Signer) Sign(req *http.Request, body []byte) error {
+	if s.Algorithm == nil {
+		return errors.New("httpsig: signer has no algorithm")
+	}
+	components := s.Components
+	if len(components) == 0 {
+		components = DefaultComponents
+		if body != nil {
+			components = append(components[:len(components):len(components)], "content-digest")
+		}
+	}
+
+	var covered []string
+	for _, name := range components {
+		name = strings.ToLower(name)
+		if name == "content-digest" && req.Header.Get("Content-Digest") == "" {
+			req.Header.Set("Content-Digest", ContentDigest(body))
+		}
+		if !strings.HasPrefix(name, "@") && len(req.Header.Values(name)) == 0 {
+			continue
+		}
+		covered = append(covered, name)
+	}
+
+	now := time.Now
+	if s.Clock != nil {
+		now = s.Clock
+	}
+	created := now().Unix()
+	params := ";created=" + strconv.FormatInt(created, 10)
+	if s.TTL > 0 {
+		params += ";expires=" + strconv.FormatInt(created+int64(s.TTL/time.Second), 10)
+	}
+	if s.Nonce {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		params += `;nonce="` + base64.RawURLEncoding.EncodeToString(nonce) + `"`
+	}
+	if s.IncludeAlg {
+		params += `;alg="` + s.Algorithm.Name() + `"`
+	}
+	if s.KeyID != "" {
+		params += `;keyid="` + s.KeyID + `"`
+	}
+	if s.Tag != "" {
+		params += `;tag="` + s.Tag + `"`
+	}
+	signatureParams := "(" + quoteList(covered) + ")" + params
+
+	base, err := signatureBase(req, covered, signatureParams)
+	if err != nil {
+		return err
+	}
+	sig, err := s.Algorithm.Sign(base)
+	if err != nil {
+		return fmt.Errorf("httpsig: sign: %w", err)
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+	req.Header.Set("Signature-Input", label+"="+signatureParams)
+	req.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// ContentDigest returns the RFC 9530 Content-Digest value of body using SHA-256.
+func ContentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// VerifyOptions configures Verify.
+type VerifyOptions struct {
+	// Keys resolves the algorithm and key for a keyid parameter (which may be
+	// empty when the signer sent none).
+	Keys func(keyID string) (Algorithm, error)
+	// Label selects the signature to check; by default the first one.
+	Label string
+	// Required components must all be covered, e.g. "@method" and
+	// "@target-uri".
+	Required []string
+	// MaxAge, if > 0, rejects signatures created longer ago, or further in
+	// the future.
+	MaxAge time.Duration
+	// Clock supplies the current time (default time.Now).
+	Clock func() time.Time
+}
+
+// Verify checks a signature on req, typically in a server handler. body is the
+// request body already read by the caller; it is checked against a covered
+// Content-Digest. The error matches ErrVerification when the signature is
+// missing, expired or invalid.
+func Verify(req *http.Request, body []byte, opts VerifyOptions) error {
+	inputs, err := parseDictionary(req.Header.Get("Signature-Input"))
+	if err != nil || len(inputs) == 0 {
+		return fmt.Errorf("%w: missing or malformed Signature-Input", ErrVerification)
+	}
+	sigs, err := parseDictionary(req.Header.Get("Signature"))
+	if err != nil {
+		return fmt.Errorf("%w: malformed Signature", ErrVerification)
+	}
+
+	input := inputs[0]
+	if opts.Label != "" {
+		input = member{}
+		for _, m := range inputs {
+			if m.key == opts.Label {
+				input = m
+			}
+		}
+		if input.key == "" {
+			return fmt.Errorf("%w: no signature labeled %q", ErrVerification, opts.Label)
+		}
+	}
+	var sig []byte
+	for _, m := range sigs {
+		if m.key == input.key && m.bytes != nil {
+			sig = m.bytes
+		}
+	}
+	if sig == nil || input.list == nil {
+		return fmt.Errorf("%w: no signature for %q", ErrVerification, input.key)
+	}
+
+	for _, name := range opts.Required {
+		if !contains(input.list, strings.ToLower(name)) {
+			return fmt.Errorf("%w: %s is not covered", ErrVerification, name)
+		}
+	}
+	now := time.Now
+	if opts.Clock != nil {
+		now = opts.Clock
+	}
+	t := now()
+	if created, ok := input.params["created"].(int64); ok && opts.MaxAge > 0 {
+		age := t.Sub(time.Unix(created, 0))
+		if age > opts.MaxAge || age < -opts.MaxAge {
+			return fmt.Errorf("%w: created outside max age", ErrVerification)
+		}
+	}
+	if expires, ok := input.params["expires"].(int64); ok && t.Unix() > expires {
+		return fmt.Errorf("%w: signature expired", ErrVerification)
+	}
+	if contains(input.list, "content-digest") && !digestMatches(req.Header.Get("Content-Digest"), body) {
+		return fmt.Errorf("%w: content digest mismatch", ErrVerification)
+	}
+
+	keyID, _ := input.params["keyid"].(string)
+	if opts.Keys == nil {
+		return errors.New("httpsig: VerifyOptions.Keys is required")
+	}
+	alg, err := opts.Keys(keyID)
+	if err != nil {
+		return err
+	}
+	if name, ok := input.params["alg"].(string); ok && name != alg.Name() {
+		return fmt.Errorf("%w: algorithm %q does not match key", ErrVerification, name)
+	}
+	base, err := signatureBase(req, input.list, input.raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	if err := alg.Verify(base, sig); err != nil {
+		return fmt.Errorf("%w: signature mismatch", ErrVerification)
+	}
+	return nil
+}
+
+// signatureBase builds the RFC 9421 signature base for the covered
+// components of req.
+func signatureBase(req *http.Request, covered []string, signatureParams string) ([]byte, error) {
+	var b strings.Builder
+	for _, name := range covered {
+		value, err := componentValue(req, name)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(`"` + name + `": ` + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + signatureParams)
+	return []byte(b.String()), nil
+}
+
+func componentValue(req *http.Request, name string) (string, error) {
+	u := req.URL
+	switch name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		target := *u
+		target.Scheme = scheme(req)
+		target.Host = authority(req)
+		target.Fragment, target.RawFragment = "", ""
+		return target.String(), nil
+	case "@authority":
+		return authority(req), nil
+	case "@scheme":
+		return scheme(req), nil
+	case "@request-target":
+		return u.RequestURI(), nil
+	case "@path":
+		if p := u.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + u.RawQuery, nil
+	}
+	if strings.HasPrefix(name, "@") || strings.ContainsAny(name, `;"`) {
+		return "", fmt.Errorf("httpsig: unsupported component %q", name)
+	}
+	values := req.Header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("httpsig: header %q is missing", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+func scheme(req *http.Request) string {
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// authority returns the lowercase host with default ports removed.
+func authority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host = strings.ToLower(host)
+	switch s := scheme(req); {
+	case s == "http" && strings.HasSuffix(host, ":80"):
+		host = strings.TrimSuffix(host, ":80")
+	case s == "https" && strings.HasSuffix(host, ":443"):
+		host = strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// digestMatches checks a Content-Digest header with sha-256 or sha-512 against
+// body. At least one supported digest must be present and all of them match.
+func digestMatches(header string, body []byte) bool {
+	digests, err := parseDictionary(header)
+	if err != nil {
+		return false
+	}
+	checked := false
+	for _, d := range digests {
+		var h crypto.Hash
+		switch d.key {
+		case "sha-256":
+			h = crypto.SHA256
+		case "sha-512":
+			h = crypto.SHA512
+		default:
+			continue
+		}
+		var sum []byte
+		if h == crypto.SHA256 {
+			s := sha256.Sum256(body)
+			sum = s[:]
+		} else {
+			s := sha512.Sum512(body)
+			sum = s[:]
+		}
+		if !hmac.Equal(sum, d.bytes) {
+			return false
+		}
+		checked = true
+	}
+	return checked
+}
+
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = `"` + n + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// member is one entry of a structured field dictionary (RFC 8941), limited
+// to what signature headers use: inner lists of strings with parameters, and
+// byte sequences.
+type member struct {
+	key    string
+	list   []string
+	bytes  []byte
+	params map[string]any
+	// raw is the member value as sent, used verbatim as @signature-params.
+	raw string
+}
+
+var errMalformed = errors.New("malformed structured field")
+
+func parseDictionary(s string) ([]member, error) {
+	p := &sfParser{s: s}
+	var members []member
+	for {
+		p.skipSpace()
+		if p.done() {
+			return members, nil
+		}
+		key := p.key()
+		if key == "" || !p.consume('=') {
+			return nil, errMalformed
+		}
+		start := p.i
+		m := member{key: key, params: map[string]any{}}
+		switch p.peek() {
+		case '(':
+			list, err := p.innerList()
+			if err != nil {
+				return nil, err
+			}
+			m.list = list
+		case ':':
+			b, err := p.byteSequence()
+			if err != nil {
+				return nil, err
+			}
+			m.bytes = b
+		default:
+			if _, err := p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.parameters(m.params); err != nil {
+			return nil, err
+		}
+		m.raw = s[start:p.i]
+		members = append(members, m)
+
+		p.skipSpace()
+		if p.done() {
+			return members, nil
+		}
+		if !p.consume(',') {
+			return nil, errMalformed
+		}
+	}
+}
+
+type sfParser struct {
+	s string
+	i int
+}
+
+func (p *sfParser) done() bool { return p.i >= len(p.s) }
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.i++
+	return true
+}
+
+func (p *sfParser) skipSpace() {
+	for !p.done() && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+func (p *sfParser) key() string {
+	start := p.i
+	for !p.done() {
+		c := p.s[p.i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+func (p *sfParser) innerList() ([]string, error) {
+	p.consume('(')
+	list := []string{}
+	for {
+		for p.consume(' ') {
+		}
+		if p.consume(')') {
+			return list, nil
+		}
+		item, err := p.bareItem()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := item.(string)
+		if !ok {
+			return nil, errMalformed
+		}
+		// Component parameters such as ;sf or ;key are not supported
+		if p.peek() == ';' {
+			return nil, errors.New("component parameters are not supported")
+		}
+		list = append(list, name)
+	}
+}
+
+func (p *sfParser) parameters(params map[string]any) error {
+	for p.consume(';') {
+		p.skipSpace()
+		key := p.key()
+		if key == "" {
+			return errMalformed
+		}
+		var value any = true
+		if p.consume('=') {
+			v, err := p.bareItem()
+			if err != nil {
+				return err
+			}
+			value = v
+		}
+		params[key] = value
+	}
+	return nil
+}
+
+func (p *sfParser) bareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		return p.str()
+	case c == ':':
+		return p.byteSequence()
+	case c == '-' || '0' <= c && c <= '9':
+		start := p.i
+		p.i++
+		for !p.done() && '0' <= p.s[p.i] && p.s[p.i] <= '9' {
+			p.i++
+		}
+		return strconv.ParseInt(p.s[start:p.i], 10, 64)
+	case c == '?':
+		p.i++
+		switch {
+		case p.consume('1'):
+			return true, nil
+		case p.consume('0'):
+			return false, nil
+		}
+	case c == '*' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		start := p.i
+		for !p.done() && !strings.ContainsRune(" \t,;()=\"", rune(p.s[p.i])) {
+			p.i++
+		}
+		return p.s[start:p.i], nil
+	}
+	return nil, errMalformed
+}
+
+func (p *sfParser) str() (string, error) {
+	p.consume('"')
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.i]
+		p.i++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.done() {
+				return "", errMalformed
+			}
+			b.WriteByte(p.s[p.i])
+			p.i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errMalformed
+}
+
+func (p *sfParser) byteSequence() ([]byte, error) {
+	p.consume(':')
+	end := strings.IndexByte(p.s[p.i:], ':')
+	if end < 0 {
+		return nil, errMalformed
+	}
+	b, err := base64.StdEncoding.DecodeString(p.s[p.i : p.i+end])
+	if err != nil {
+		return nil, errMalformed
+	}
+	p.i += end + 1
+	return b, nil
+}
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader lets servers recognize repeated deliveries of one
+// non-idempotent request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// retrySafe reports whether repeating req cannot cause duplicate side
+// effects: its method is idempotent or it carries an Idempotency-Key. A POST
+// that timed out may have been processed, and repeating it could charge or
+// create twice.
+func retrySafe(req *http.Request) bool {
+	return IsIdempotent(req.Method) || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// IsIdempotent reports whether method is idempotent per RFC 9110.
+func IsIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// setIdempotencyKey gives POST and PATCH requests without one a fresh
+// Idempotency-Key, so they become safe to retry.
+func setIdempotencyKey(req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodPatch {
+		return
+	}
+	if req.Header.Get(IdempotencyKeyHeader) == "" {
+		req.Header.Set(IdempotencyKeyHeader, newUUIDv7())
+	}
+}
+
+// newUUIDv7 returns a version 7 UUID, which sorts by creation time and so
+// keeps server-side idempotency indexes compact.
+func newUUIDv7() string {
+	var b [16]byte
+	rand.Read(b[6:])
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(b[:6], ms[2:])
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"httpclient/metrics"
+)
+
+// inFlightLimiter caps concurrent requests overall and per host. Slots are
+// held for the whole request, including retries, and for streamed responses
+// until the body is closed.
+type inFlightLimiter struct {
+	total    chan struct{}
+	perHost  int
+	mu       sync.Mutex
+	hosts    map[string]*hostSlots
+	recorder metrics.Recorder
+}
+
+type hostSlots struct {
+	slots chan struct{}
+	// users counts holders and waiters, so idle hosts can be dropped
+	users int
+}
+
+// newInFlightLimiter returns nil when neither limit is set.
+func newInFlightLimiter(maxInFlight, maxPerHost int, recorder metrics.Recorder) *inFlightLimiter {
+	if maxInFlight <= 0 && maxPerHost <= 0 {
+		return nil
+	}
+	l := &inFlightLimiter{perHost: maxPerHost, hosts: make(map[string]*hostSlots), recorder: recorder}
+	if maxInFlight > 0 {
+		l.total = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// acquire blocks until a slot for host is free or ctx is done. The returned
+// release func is safe to call more than once.
+func (l *inFlightLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	var h *hostSlots
+	if l.perHost > 0 {
+		l.mu.Lock()
+		h = l.hosts[host]
+		if h == nil {
+			h = &hostSlots{slots: make(chan struct{}, l.perHost)}
+			l.hosts[host] = h
+		}
+		h.users++
+		l.mu.Unlock()
+		if err := acquireSlot(ctx, h.slots); err != nil {
+			l.leave(host, h)
+			l.observeWait(host, "timeout", start)
+			return nil, fmt.Errorf("httpclient: waiting for an in-flight slot for %s: %w", host, err)
+		}
+	}
+	if l.total != nil {
+		if err := acquireSlot(ctx, l.total); err != nil {
+			if h != nil {
+				<-h.slots
+				l.leave(host, h)
+			}
+			l.observeWait(host, "timeout", start)
+			return nil, fmt.Errorf("httpclient: waiting for an in-flight slot: %w", err)
+		}
+	}
+	l.observeWait(host, "acquired", start)
+	l.addInFlight(host, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.total != nil {
+				<-l.total
+			}
+			if h != nil {
+				<-h.slots
+				l.leave(host, h)
+			}
+			l.addInFlight(host, -1)
+		})
+	}, nil
+}
+
+// leave drops a holder or waiter of host, forgetting the host when idle.
+func (l *inFlightLimiter) leave(host string, h *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h.users--; h.users == 0 {
+		delete(l.hosts, host)
+	}
+}
+
+func (l *inFlightLimiter) observeWait(host, outcome string, start time.Time) {
+	if rec, ok := l.recorder.(metrics.ConcurrencyRecorder); ok {
+		rec.ObserveInFlightWait(host, outcome, time.Since(start))
+	}
+}
+
+func (l *inFlightLimiter) addInFlight(host string, delta int) {
+	if rec, ok := l.recorder.(metrics.ConcurrencyRecorder); ok {
+		rec.AddInFlight(host, delta)
+	}
+}
+
+// acquireSlot takes a slot from sem, giving up when ctx is done.
+func acquireSlot(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,207 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"httpclient/utils"
+)
+
+// recentErrorLimit is how many failed requests a client remembers for Info.
+const recentErrorLimit = 20
+
+// ClientInfo is a snapshot of how a client is configured and how it has
+// behaved, for operators. Credentials are never included.
+type ClientInfo struct {
+	BaseURL    string `json:"base_url,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// Middleware lists the middleware chain in execution order, by function name.
+	Middleware []string `json:"middleware"`
+	// Auth describes how requests are authenticated, e.g. "Bearer ****" or
+	// "refresh"; "none" when no credentials are configured.
+	Auth              string                  `json:"auth"`
+	Timeout           time.Duration           `json:"timeout_ns"`
+	MaxRetries        int                     `json:"max_retries"`
+	RetryBackoff      time.Duration           `json:"retry_backoff_ns"`
+	MaxRetryAfter     time.Duration           `json:"max_retry_after_ns"`
+	PerAttemptTimeout time.Duration           `json:"per_attempt_timeout_ns,omitempty"`
+	OverallTimeout    time.Duration           `json:"overall_timeout_ns,omitempty"`
+	Breakers          map[string]BreakerState `json:"breakers,omitempty"`
+	DNSCache          *DNSCacheStats          `json:"dns_cache,omitempty"`
+	// Features lists the optional behaviors that are enabled, e.g. "hedging".
+	Features     []string       `json:"features,omitempty"`
+	Stats        Stats          `json:"stats"`
+	RecentErrors []ErrorSummary `json:"recent_errors"`
+}
+
+// ErrorSummary describes one failed request: a transport error or a final
+// 5xx response after retries.
+type ErrorSummary struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Info returns the client's current configuration and recent errors, newest
+// error first.
+func (c *CommonHTTPClient) Info() ClientInfo {
+	info := ClientInfo{
+		PathPrefix:        c.pathPrefix,
+		Auth:              c.authMode(),
+		Timeout:           c.client.Timeout,
+		MaxRetries:        c.maxRetries,
+		RetryBackoff:      c.retryBackoff,
+		MaxRetryAfter:     c.maxRetryAfter,
+		PerAttemptTimeout: c.perAttemptTimeout,
+		OverallTimeout:    c.overallTimeout,
+		Features:          c.features(),
+		Stats:             c.Stats(),
+		RecentErrors:      c.recentErrors.snapshot(),
+	}
+	if c.baseURL != nil {
+		info.BaseURL = c.redactor.URL(c.baseURL)
+	}
+	for _, mw := range c.middleware {
+		info.Middleware = append(info.Middleware, funcName(mw))
+	}
+	if c.breakers != nil {
+		info.Breakers = c.breakers.States()
+	}
+	if c.dnsCache != nil {
+		stats := c.dnsCache.Stats()
+		info.DNSCache = &stats
+	}
+	return info
+}
+
+// authMode describes the configured credentials without revealing them.
+func (c *CommonHTTPClient) authMode() string {
+	if c.refreshAuth != nil {
+		return "refresh"
+	}
+	for k, v := range c.defaultHeaders {
+		if strings.EqualFold(k, "Authorization") {
+			return utils.MaskValue(v)
+		}
+	}
+	return "none"
+}
+
+// features lists the enabled optional behaviors.
+func (c *CommonHTTPClient) features() []string {
+	var out []string
+	add := func(name string, on bool) {
+		if on {
+			out = append(out, name)
+		}
+	}
+	add("scheduler", c.scheduler != nil)
+	add("rate_limits", c.rateLimiter != nil)
+	add("tenants", c.tenantResolver != nil)
+	add("host_policy", c.hostPolicy != nil)
+	add("metrics", c.metrics != nil)
+	add("hedging", c.hedging != nil)
+	add("checksums", c.verifyChecksums)
+	add("mirror", c.mirrorConfig != nil)
+	add("endpoints", c.endpoints != nil)
+	add("negative_cache", c.negativeCache != nil)
+	add("log_escalation", c.escalator != nil)
+	add("idempotency_keys", c.idempotencyKeys)
+	add("hooks", c.hooks != nil)
+	add("dns_cache", c.dnsCache != nil)
+	add("discovery", c.discovery != nil)
+	add("health_probe", c.healthProbe != nil)
+	add("in_flight_limit", c.inFlight != nil)
+	add("log_sampling", c.logSampler != nil)
+	add("cookie_jar", c.client.Jar != nil)
+	return out
+}
+
+// funcName returns the short name of mw's underlying function, e.g.
+// "httpclient.BodyTransforms.func1".
+func funcName(mw Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// IntrospectionHandler serves the Info of the named clients as JSON, e.g. on
+// an admin port. A "client" query parameter selects a single client.
+func IntrospectionHandler(clients map[string]*CommonHTTPClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if name := r.URL.Query().Get("client"); name != "" {
+			c, ok := clients[name]
+			if !ok {
+				http.Error(w, `{"error":"unknown client"}`, http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(c.Info())
+			return
+		}
+		out := make(map[string]ClientInfo, len(clients))
+		for name, c := range clients {
+			out[name] = c.Info()
+		}
+		json.NewEncoder(w).Encode(out)
+	})
+}
+
+// errorRing keeps the most recent failed requests.
+type errorRing struct {
+	mu      sync.Mutex
+	entries []ErrorSummary
+	next    int
+}
+
+func (e *errorRing) add(s ErrorSummary) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.entries) < recentErrorLimit {
+		e.entries = append(e.entries, s)
+		return
+	}
+	e.entries[e.next] = s
+	e.next = (e.next + 1) % recentErrorLimit
+}
+
+// snapshot returns the entries newest first.
+func (e *errorRing) snapshot() []ErrorSummary {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]ErrorSummary, 0, len(e.entries))
+	for i := range len(e.entries) {
+		idx := (e.next - 1 - i + 2*len(e.entries)) % len(e.entries)
+		out = append(out, e.entries[idx])
+	}
+	return out
+}
+
+// recordError remembers a failed request for Info.
+func (c *CommonHTTPClient) recordError(ev LogEvent) {
+	s := ErrorSummary{
+		Time:       time.Now(),
+		RequestID:  ev.RequestID,
+		Method:     ev.Method,
+		URL:        ev.URL,
+		StatusCode: ev.StatusCode,
+	}
+	if ev.Err != nil {
+		s.Error = ev.Err.Error()
+	}
+	c.recentErrors.add(s)
+}
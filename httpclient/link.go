@@ -0,0 +1,215 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Link is one target of an RFC 8288 Link header.
+type Link struct {
+	// URL is the target, resolved against the request URL when known.
+	URL string
+	// Rel holds the relation types, lower-cased, e.g. ["next"].
+	Rel []string
+	// Params holds the other target attributes, such as "title" or "type",
+	// keyed by lower-cased name.
+	Params map[string]string
+}
+
+// Links are the links of a response. Next, Prev, First and Last hold the
+// URLs of the pagination relations GitHub-style APIs use, or "".
+type Links struct {
+	Next  string
+	Prev  string
+	First string
+	Last  string
+	// Rels maps every relation type, lower-cased, to its links in header
+	// order.
+	Rels map[string][]Link
+}
+
+// ParseLinks parses the Link headers of resp. Malformed links are skipped.
+func ParseLinks(resp *http.Response) Links {
+	var base *url.URL
+	if resp.Request != nil {
+		base = resp.Request.URL
+	}
+	var links Links
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range ParseLinkHeader(header, base) {
+			links.add(link)
+		}
+	}
+	return links
+}
+
+// ParseLinkHeader parses one Link header value, resolving targets against
+// base when it is not nil.
+func ParseLinkHeader(header string, base *url.URL) []Link {
+	var out []Link
+	p := linkParser{s: header}
+	for {
+		p.skip(" \t,")
+		if p.done() {
+			return out
+		}
+		link, ok := p.link()
+		if !ok {
+			// Resynchronize at the next link
+			p.until(',')
+			continue
+		}
+		if base != nil {
+			u, err := base.Parse(link.URL)
+			if err != nil {
+				continue
+			}
+			link.URL = u.String()
+		}
+		out = append(out, link)
+	}
+}
+
+// Get returns the URL of the first link with relation rel, or "".
+func (l Links) Get(rel string) string {
+	if links := l.Rels[strings.ToLower(rel)]; len(links) > 0 {
+		return links[0].URL
+	}
+	return ""
+}
+
+func (l *Links) add(link Link) {
+	if l.Rels == nil {
+		l.Rels = make(map[string][]Link)
+	}
+	for _, rel := range link.Rel {
+		l.Rels[rel] = append(l.Rels[rel], link)
+		var field *string
+		switch rel {
+		case "next":
+			field = &l.Next
+		case "prev", "previous":
+			field = &l.Prev
+		case "first":
+			field = &l.First
+		case "last":
+			field = &l.Last
+		default:
+			continue
+		}
+		if *field == "" {
+			*field = link.URL
+		}
+	}
+}
+
+// linkParser reads the link-value list grammar of RFC 8288, section 3.
+type linkParser struct {
+	s   string
+	pos int
+}
+
+func (p *linkParser) done() bool { return p.pos >= len(p.s) }
+
+func (p *linkParser) skip(chars string) {
+	for !p.done() && strings.IndexByte(chars, p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// until advances to the next c outside of a quoted string, or to the end.
+func (p *linkParser) until(c byte) {
+	for !p.done() && p.s[p.pos] != c {
+		if p.s[p.pos] == '"' {
+			p.quoted()
+			continue
+		}
+		p.pos++
+	}
+}
+
+// link reads "<target>" followed by ";"-separated parameters.
+func (p *linkParser) link() (Link, bool) {
+	if p.s[p.pos] != '<' {
+		return Link{}, false
+	}
+	end := strings.IndexByte(p.s[p.pos:], '>')
+	if end < 0 {
+		p.pos = len(p.s)
+		return Link{}, false
+	}
+	link := Link{URL: strings.TrimSpace(p.s[p.pos+1 : p.pos+end])}
+	p.pos += end + 1
+	for {
+		p.skip(" \t")
+		if p.done() || p.s[p.pos] == ',' {
+			return link, true
+		}
+		if p.s[p.pos] != ';' {
+			return Link{}, false
+		}
+		p.pos++
+		p.skip(" \t")
+		name := strings.ToLower(p.token())
+		if name == "" {
+			return Link{}, false
+		}
+		p.skip(" \t")
+		value := ""
+		if !p.done() && p.s[p.pos] == '=' {
+			p.pos++
+			p.skip(" \t")
+			if !p.done() && p.s[p.pos] == '"' {
+				value = p.quoted()
+			} else {
+				value = p.token()
+			}
+		}
+		if name == "rel" {
+			// Only the first rel parameter counts
+			if link.Rel == nil {
+				for _, rel := range strings.Fields(value) {
+					link.Rel = append(link.Rel, strings.ToLower(rel))
+				}
+			}
+			continue
+		}
+		if link.Params == nil {
+			link.Params = make(map[string]string)
+		}
+		if _, seen := link.Params[name]; !seen {
+			link.Params[name] = value
+		}
+	}
+}
+
+// token reads up to the next separator.
+func (p *linkParser) token() string {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t;,=\"", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// quoted reads a quoted-string starting at the opening quote, unescaping
+// quoted pairs.
+func (p *linkParser) quoted() string {
+	var b strings.Builder
+	for p.pos++; !p.done(); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '"':
+			p.pos++
+			return b.String()
+		case '\\':
+			if p.pos+1 < len(p.s) {
+				p.pos++
+			}
+			b.WriteByte(p.s[p.pos])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
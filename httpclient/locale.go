@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type localeKey struct{}
+
+// WithLocale returns a context carrying the caller's preferred languages, most
+// preferred first (e.g. "de-DE", "en"). Requests made with it send a matching
+// Accept-Language header unless the request sets one explicitly.
+func WithLocale(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, tags)
+}
+
+// LocaleFromContext returns the languages stored by WithLocale.
+func LocaleFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(localeKey{}).([]string)
+	return tags
+}
+
+// FormatAcceptLanguage renders language tags, most preferred first, as an
+// Accept-Language value with decreasing quality values:
+// "de-DE, de;q=0.9, en;q=0.8".
+func FormatAcceptLanguage(tags ...string) string {
+	parts := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0 - 0.1*float64(i)
+		if i == 0 {
+			parts = append(parts, tag)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s;q=%.1f", tag, max(q, 0.1)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ContentLanguage returns the languages listed in the response
+// Content-Language header, e.g. ["en-US", "fr"].
+func ContentLanguage(resp *http.Response) []string {
+	var tags []string
+	for _, v := range resp.Header.Values("Content-Language") {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// requestLanguage picks the Accept-Language for a request: the request option,
+// then the context locale, then the client default.
+func (c *CommonHTTPClient) requestLanguage(ctx context.Context, opts RequestOptions) string {
+	if opts.AcceptLanguage != "" {
+		return opts.AcceptLanguage
+	}
+	if tags := LocaleFromContext(ctx); len(tags) > 0 {
+		return FormatAcceptLanguage(tags...)
+	}
+	return c.acceptLanguage
+}
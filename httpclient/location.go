@@ -0,0 +1,73 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNoLocation is returned by FollowLocation when the response is not a 201
+// or 202 carrying a Location header.
+var ErrNoLocation = errors.New("response has no Location to follow")
+
+// FollowOptions controls how FollowLocation waits for a created resource to
+// become readable on eventually consistent APIs.
+type FollowOptions struct {
+	// MaxAttempts bounds GETs answered with 404 (default 5).
+	MaxAttempts int
+	// Interval is the wait between attempts (default 500ms).
+	Interval time.Duration
+}
+
+// FollowLocation GETs the resource named by the Location header of a 201 or
+// 202 response and decodes it into T, retrying 404s until the resource shows
+// up. Relative locations are resolved against the original request URL.
+func FollowLocation[T any](ctx context.Context, c *CommonHTTPClient, resp *http.Response, fo FollowOptions) (T, error) {
+	var out T
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		return out, ErrNoLocation
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return out, ErrNoLocation
+	}
+	if fo.MaxAttempts <= 0 {
+		fo.MaxAttempts = 5
+	}
+	if fo.Interval <= 0 {
+		fo.Interval = 500 * time.Millisecond
+	}
+
+	opts := RequestOptions{Method: http.MethodGet, Path: location.String()}
+	for attempt := 1; ; attempt++ {
+		out, _, err = SendJSON[T](ctx, c, opts, nil)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || attempt >= fo.MaxAttempts {
+			return out, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return out, ctx.Err()
+		case <-time.After(fo.Interval):
+		}
+	}
+}
+
+// CreateJSON sends body with opts (typically a POST) and, when the server
+// answers 201/202 with a Location, fetches and decodes the created resource.
+// Other successful responses are decoded directly into T.
+func CreateJSON[T any](ctx context.Context, c *CommonHTTPClient, opts RequestOptions, body any, fo FollowOptions) (T, error) {
+	if c == nil {
+		c = DefaultClient()
+	}
+	out, resp, err := SendJSON[T](ctx, c, opts, body)
+	if err != nil {
+		return out, err
+	}
+	if resp.Header.Get("Location") == "" {
+		return out, nil
+	}
+	return FollowLocation[T](ctx, c, resp, fo)
+}
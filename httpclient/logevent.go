@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LogLevels sets the level of the client's request log events; nil fields
+// keep the defaults. Hosts with escalated logging (see LogEscalation) log
+// these events at Info or above.
+type LogLevels struct {
+	// Request is used for "Outgoing request" (default Debug).
+	Request slog.Leveler
+	// Response is used for "Incoming response" below status 400 (default
+	// Debug), ErrorResponse for the rest (default Info).
+	Response      slog.Leveler
+	ErrorResponse slog.Leveler
+	// Retry is used for "Retrying request" (default Warn).
+	Retry slog.Leveler
+	// Failure is used for failed requests and body reads (default Error).
+	Failure slog.Leveler
+	// Slow is used for responses slower than ClientConfig.SlowRequestThreshold,
+	// unless the response level is higher (default Warn).
+	Slow slog.Leveler
+}
+
+// withDefaults fills unset levels.
+func (l LogLevels) withDefaults() LogLevels {
+	set := func(level *slog.Leveler, def slog.Level) {
+		if *level == nil {
+			*level = def
+		}
+	}
+	set(&l.Request, slog.LevelDebug)
+	set(&l.Response, slog.LevelDebug)
+	set(&l.ErrorResponse, slog.LevelInfo)
+	set(&l.Retry, slog.LevelWarn)
+	set(&l.Failure, slog.LevelError)
+	set(&l.Slow, slog.LevelWarn)
+	return l
+}
+
+// LogEvent is the structured payload shared by the client's request and
+// response log lines. RequestID ties every line of one request together and
+// matches the X-Request-ID header sent to the server.
+type LogEvent struct {
+	RequestID string
+	// Attempt is the 1-based attempt number, or 0 before the first attempt.
+	Attempt int
+	Method  string
+	URL     string
+	// Route is the route template of the request, if any.
+	Route      string
+	Duration   time.Duration
+	StatusCode int
+	// Retryable reports whether the client will try the request again.
+	Retryable bool
+	Err       error
+	// Timings breaks down the attempt by phase, when known.
+	Timings *Timings
+}
+
+// Attrs returns the event as slog attributes, omitting unset fields.
+func (e LogEvent) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("request_id", e.RequestID),
+		slog.String("method", e.Method),
+		slog.String("url", e.URL),
+	}
+	if e.Route != "" {
+		attrs = append(attrs, slog.String("route", e.Route))
+	}
+	if e.Attempt > 0 {
+		attrs = append(attrs, slog.Int("attempt", e.Attempt))
+	}
+	if e.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.StatusCode != 0 {
+		attrs = append(attrs, slog.Int("status_code", e.StatusCode))
+	}
+	if e.Attempt > 0 {
+		attrs = append(attrs, slog.Bool("retryable", e.Retryable))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	if e.Timings != nil {
+		attrs = append(attrs, slog.Any("timings", *e.Timings))
+	}
+	return attrs
+}
+
+// logEvent writes ev and any extra attributes at the given level.
+func (c *CommonHTTPClient) logEvent(ctx context.Context, level slog.Level, msg string, ev LogEvent, extra ...slog.Attr) {
+	attrs := append(ev.Attrs(), extra...)
+	if c.contextAttrs != nil {
+		attrs = append(attrs, c.contextAttrs(ctx)...)
+	}
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,36 @@
+package httpclient
+
+import "sync/atomic"
+
+// LogSampling thins out the request logs of busy clients: only one in Every
+// requests logs its "Outgoing request" and "Incoming response" lines.
+// Responses with status 400 and above, slow responses (see
+// SlowRequestThreshold) and hosts with escalated logging are always logged,
+// as are retries and failures.
+type LogSampling struct {
+	// Every is the sampling rate, e.g. 100 for 1%. Values below 2 log every
+	// request.
+	Every int
+}
+
+// logSampler picks the requests whose logs are kept.
+type logSampler struct {
+	every uint64
+	n     atomic.Uint64
+}
+
+// newLogSampler returns nil when every request is logged.
+func newLogSampler(cfg *LogSampling) *logSampler {
+	if cfg == nil || cfg.Every < 2 {
+		return nil
+	}
+	return &logSampler{every: uint64(cfg.Every)}
+}
+
+// sample reports whether the next request is logged, starting with the first.
+func (s *logSampler) sample() bool {
+	if s == nil {
+		return true
+	}
+	return (s.n.Add(1)-1)%s.every == 0
+}
@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMaxURLLength is used when a QueryFallback is configured without
+// ClientConfig.MaxURLLength. Many proxies reject URLs beyond a few KiB.
+const defaultMaxURLLength = 4096
+
+// QueryFallback rewrites a request whose URL would exceed the configured
+// length, typically into the API's documented POST-search equivalent.
+type QueryFallback func(opts RequestOptions) (RequestOptions, error)
+
+// PostFormFallback returns a QueryFallback that sends the query parameters as
+// an application/x-www-form-urlencoded POST body to path (or the original
+// path when path is empty).
+func PostFormFallback(path string) QueryFallback {
+	return func(opts RequestOptions) (RequestOptions, error) {
+		form := url.Values{}
+		for k, v := range opts.QueryParams {
+			form.Set(k, v)
+		}
+
+		alt := opts
+		alt.Method = http.MethodPost
+		if path != "" {
+			alt.Path = path
+		}
+		alt.QueryParams = nil
+		alt.GetBody = nil
+		alt.Body = strings.NewReader(form.Encode())
+		alt.Headers = make(map[string]string, len(opts.Headers)+1)
+		for k, v := range opts.Headers {
+			alt.Headers[k] = v
+		}
+		alt.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+		return alt, nil
+	}
+}
+
+// queryFallbackFor returns the fallback applying to opts, if any.
+func (c *CommonHTTPClient) queryFallbackFor(opts RequestOptions) QueryFallback {
+	if opts.DisableQueryFallback {
+		return nil
+	}
+	if opts.QueryFallback != nil {
+		return opts.QueryFallback
+	}
+	return c.queryFallback
+}
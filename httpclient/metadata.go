@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attempt describes a single try of a request.
+type Attempt struct {
+	Start      time.Time
+	Duration   time.Duration
+	StatusCode int
+	Err        error
+	Timings    Timings
+}
+
+// String renders the attempt as "15:04:05.000 120ms: status 503" or with the
+// transport error in place of the status.
+func (a Attempt) String() string {
+	outcome := fmt.Sprintf("status %d", a.StatusCode)
+	if a.Err != nil {
+		outcome = a.Err.Error()
+	}
+	return fmt.Sprintf("%s %s: %s", a.Start.Format("15:04:05.000"), a.Duration.Round(time.Millisecond), outcome)
+}
+
+// ResponseMetadata explains how a response was obtained. Retrieve it with
+// Metadata for responses, or errors.As with *RequestError for failures.
+type ResponseMetadata struct {
+	Attempts      []Attempt
+	TotalDuration time.Duration
+	// RemoteAddr is the address of the connection used by the last attempt.
+	RemoteAddr string
+	// Proto is the protocol of the final response, e.g. "HTTP/2.0".
+	Proto string
+	// Timings breaks down the last attempt, including the body transfer once
+	// the client read it.
+	Timings Timings
+	// Exhausted reports every attempt when the retries ran out while the
+	// returned response was still retryable, e.g. a final 503. It is nil
+	// otherwise.
+	Exhausted *RetryError
+
+	mu sync.Mutex
+}
+
+// AttemptCount returns the number of attempts made.
+func (m *ResponseMetadata) AttemptCount() int {
+	return len(m.Attempts)
+}
+
+// RequestError wraps the final error of a request with its metadata.
+type RequestError struct {
+	Metadata *ResponseMetadata
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	if _, ok := e.Err.(*RetryError); ok {
+		return fmt.Sprintf("%v\ntotal %s", e.Err, e.Metadata.TotalDuration)
+	}
+	return fmt.Sprintf("%v (after %d attempts in %s)", e.Err, e.Metadata.AttemptCount(), e.Metadata.TotalDuration)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// ErrRetriesExhausted is the final error of ResponseMetadata.Exhausted.
+var ErrRetriesExhausted = errors.New("retries exhausted")
+
+// RetryError reports a request that failed after several attempts. It wraps
+// the final error and every attempt's error, so errors.Is and errors.As match
+// any of them.
+type RetryError struct {
+	Attempts []Attempt
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v; %d attempts:", e.Err, len(e.Attempts))
+	for i, a := range e.Attempts {
+		fmt.Fprintf(&b, "\n  #%d %s", i+1, a)
+	}
+	return b.String()
+}
+
+func (e *RetryError) Unwrap() []error {
+	errs := []error{e.Err}
+	for _, a := range e.Attempts {
+		if a.Err != nil && a.Err != e.Err {
+			errs = append(errs, a.Err)
+		}
+	}
+	return errs
+}
+
+// finalError wraps err with the attempt history when the request was retried.
+func (m *ResponseMetadata) finalError(err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Attempts) < 2 {
+		return err
+	}
+	return &RetryError{Attempts: append([]Attempt(nil), m.Attempts...), Err: err}
+}
+
+// exhaust records the attempt report for a response returned after the
+// retries ran out, and reports whether the request was retried at all.
+func (m *ResponseMetadata) exhaust(status int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Attempts) < 2 {
+		return false
+	}
+	m.Exhausted = &RetryError{
+		Attempts: append([]Attempt(nil), m.Attempts...),
+		Err:      fmt.Errorf("%w: status %d", ErrRetriesExhausted, status),
+	}
+	return true
+}
+
+// exhaustedError wraps err, built from a response returned after the retries
+// ran out, in the same attempt report as a request failing after retries.
+func exhaustedError(resp *http.Response, err error) error {
+	meta := Metadata(resp)
+	if meta == nil || meta.Exhausted == nil {
+		return err
+	}
+	return &RequestError{Metadata: meta, Err: &RetryError{Attempts: meta.Exhausted.Attempts, Err: err}}
+}
+
+type metadataKey struct{}
+
+// Metadata returns the metadata attached to a response returned by the client,
+// or nil for responses from elsewhere.
+func Metadata(resp *http.Response) *ResponseMetadata {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	meta, _ := resp.Request.Context().Value(metadataKey{}).(*ResponseMetadata)
+	return meta
+}
+
+// MetadataFromError returns the metadata carried by a *RequestError in err's chain.
+func MetadataFromError(err error) *ResponseMetadata {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Metadata
+	}
+	return nil
+}
+
+// withMetadata returns req with metadata attached to its context and a trace
+// recording the remote address of every connection used.
+func withMetadata(req *http.Request) (*http.Request, *ResponseMetadata) {
+	meta := &ResponseMetadata{}
+	ctx := context.WithValue(req.Context(), metadataKey{}, meta)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			meta.mu.Lock()
+			meta.RemoteAddr = info.Conn.RemoteAddr().String()
+			meta.mu.Unlock()
+		},
+	})
+	return req.WithContext(ctx), meta
+}
+
+// addAttempt records the outcome of one attempt.
+func (m *ResponseMetadata) addAttempt(start time.Time, resp *http.Response, err error, timings Timings) {
+	a := Attempt{Start: start, Duration: time.Since(start), Err: err, Timings: timings}
+	if resp != nil {
+		a.StatusCode = resp.StatusCode
+	}
+	m.mu.Lock()
+	m.Attempts = append(m.Attempts, a)
+	m.Timings = timings
+	m.mu.Unlock()
+}
@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestRetriesExhaustedOnStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRetries    int
+		errorOnNon2xx bool
+		// wantAttempts is the length of the attempt report, 0 for none
+		wantAttempts int
+	}{
+		{name: "response returned", maxRetries: 2, wantAttempts: 3},
+		{name: "APIError returned", maxRetries: 2, errorOnNon2xx: true, wantAttempts: 3},
+		{name: "no retries configured", errorOnNon2xx: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/items").Reply(http.StatusServiceUnavailable, "down")
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:       base,
+				Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient:    &http.Client{Transport: mock},
+				MaxRetries:    tt.maxRetries,
+				RetryBackoff:  time.Millisecond,
+				ErrorOnNon2xx: tt.errorOnNon2xx,
+			})
+
+			resp, err := c.Do(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/items"})
+			var report *RetryError
+			if tt.errorOnNon2xx {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+					t.Fatalf("error = %v, want a 503 *APIError", err)
+				}
+				errors.As(err, &report)
+				if report != nil && MetadataFromError(err) == nil {
+					t.Error("attempt report without metadata")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Do: %v", err)
+				}
+				resp.Body.Close()
+				report = Metadata(resp).Exhausted
+				if report != nil && !errors.Is(report, ErrRetriesExhausted) {
+					t.Errorf("report error = %v, want ErrRetriesExhausted", report)
+				}
+			}
+
+			if tt.wantAttempts == 0 {
+				if report != nil {
+					t.Errorf("attempt report = %v, want none", report)
+				}
+				return
+			}
+			if report == nil {
+				t.Fatal("no attempt report")
+			}
+			if len(report.Attempts) != tt.wantAttempts {
+				t.Errorf("reported attempts = %d, want %d", len(report.Attempts), tt.wantAttempts)
+			}
+			if msg := report.Error(); !strings.Contains(msg, "status 503") || !strings.Contains(msg, "#3") {
+				t.Errorf("report = %s, want every attempt's status", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package httpclient
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Doer sends a prepared request and returns its response.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts an ordinary function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer to add cross-cutting behavior such as auth refresh,
+// metrics or custom headers. The wrapped Doer covers the whole request,
+// including retries; responses it returns have a re-readable body.
+type Middleware func(next Doer) Doer
+
+// Use appends middleware to the chain. Middleware runs in the order added:
+// the first one sees the request first and the response last. Use is not safe
+// to call concurrently with requests and does not affect clients derived
+// earlier with Scoped.
+func (c *CommonHTTPClient) Use(mw ...Middleware) {
+	c.middleware = append(slices.Clip(c.middleware), mw...)
+}
+
+// dispatch sends req through the middleware chain and the core pipeline,
+// assigning a request ID and, if enabled, an Idempotency-Key first so
+// middleware can read them.
+func (c *CommonHTTPClient) dispatch(req *http.Request) (*http.Response, error) {
+	req = withRequestID(req)
+	if c.idempotencyKeys {
+		setIdempotencyKey(req)
+	}
+	var d Doer = DoerFunc(c.send)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		d = c.middleware[i](d)
+	}
+	return d.Do(req)
+}
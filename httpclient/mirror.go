@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+
+	"httpclient/metrics"
+	"httpclient/utils"
+	"log/slog"
+)
+
+// MirrorConfig duplicates a share of requests to a secondary backend, e.g. to
+// test a new implementation against production traffic. Mirrored requests are
+// sent asynchronously, bypass middleware and retries, and their responses are
+// discarded. Requests with a body that cannot be replayed are never mirrored.
+type MirrorConfig struct {
+	BaseURL *url.URL
+	// Percent of requests to mirror, from 0 to 100.
+	Percent float64
+	// Timeout bounds each mirrored request (default 30s).
+	Timeout time.Duration
+	// Metrics records mirrored requests separately from primary traffic.
+	Metrics metrics.Recorder
+	// Client sends mirrored requests (default http.DefaultClient).
+	Client *http.Client
+}
+
+// mirror sends a copy of req to the mirror backend when it is sampled.
+// path is the request path relative to the client base URL.
+func (c *CommonHTTPClient) mirror(req *http.Request, path string) {
+	m := c.mirrorConfig
+	if m == nil || m.Percent <= 0 || rand.Float64()*100 >= m.Percent {
+		return
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return
+	}
+	target, err := utils.JoinURL(m.BaseURL, path)
+	if err != nil {
+		c.logger.Warn("Cannot build mirror URL", slog.String("path", path), slog.Any("error", err))
+		return
+	}
+	target.RawQuery = req.URL.RawQuery
+
+	// The mirror outlives the primary request, so detach from its cancellation
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), timeout)
+	shadow := req.Clone(ctx)
+	shadow.URL = target
+	shadow.Host = ""
+	if req.GetBody != nil {
+		if shadow.Body, err = req.GetBody(); err != nil {
+			cancel()
+			return
+		}
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	go func() {
+		defer cancel()
+		start := time.Now()
+		status := 0
+		resp, err := client.Do(shadow)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			status = resp.StatusCode
+		} else {
+			c.logger.Debug("Mirrored request failed", slog.String("url", c.redactor.URL(target)), slog.Any("error", err))
+		}
+		if m.Metrics != nil {
+			m.Metrics.ObserveRequest(shadow.Method, target.Host, status, time.Since(start))
+		}
+	}()
+}
@@ -0,0 +1,73 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// negativeTTL returns how long a 404 or 410 response to req may be cached.
+func (c *CommonHTTPClient) negativeTTL(req *http.Request) time.Duration {
+	if p := c.endpoints.match(req); p != nil && p.NegativeCacheTTL > 0 {
+		return p.NegativeCacheTTL
+	}
+	return c.negativeCacheTTL
+}
+
+// negativeCacheMiddleware serves cached 404 and 410 responses to GET and HEAD
+// requests, so hot lookups of missing resources do not reach the upstream.
+// Entries are kept per caller, like those of EndpointPolicy.CacheTTL.
+// Successful writes to a URL drop its entries for every caller.
+func (c *CommonHTTPClient) negativeCacheMiddleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		key := c.negativeCache.key(req.Method, req)
+		lookup := req.Method == http.MethodGet || req.Method == http.MethodHead
+		if lookup {
+			if resp, ok := c.negativeCache.get(key, req); ok {
+				return resp, nil
+			}
+		}
+
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !lookup && resp.StatusCode < 300:
+			c.InvalidateNegativeCache(req.URL.String())
+		case lookup && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone):
+			ttl := c.negativeTTL(req)
+			if ttl <= 0 || isStreaming(req.Context()) || !cacheable(resp) {
+				break
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			c.negativeCache.put(key, req, resp, body, ttl)
+		}
+		return resp, nil
+	})
+}
+
+// InvalidateNegativeCache drops cached 404 and 410 responses for the absolute
+// URL, for every caller, e.g. after creating the resource through another
+// channel.
+func (c *CommonHTTPClient) InvalidateNegativeCache(url string) {
+	if c.negativeCache == nil {
+		return
+	}
+	c.negativeCache.deleteURL(http.MethodGet, url)
+	c.negativeCache.deleteURL(http.MethodHead, url)
+}
+
+// ClearNegativeCache drops every cached 404 and 410 response.
+func (c *CommonHTTPClient) ClearNegativeCache() {
+	if c.negativeCache == nil {
+		return
+	}
+	c.negativeCache.clear()
+}
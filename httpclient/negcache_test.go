@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func newNegativeCacheTestClient(mock *httpmock.Mock) *CommonHTTPClient {
+	base, _ := url.Parse("http://api.test")
+	return NewCommonHTTPClient(ClientConfig{
+		BaseURL:          base,
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HTTPClient:       &http.Client{Transport: mock},
+		NegativeCacheTTL: time.Minute,
+		CacheKeyHeaders:  []string{"X-Api-Key"},
+	})
+}
+
+func TestNegativeCacheKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		first, next cacheCall
+		wantCalls   int
+	}{
+		{
+			name:      "same credentials share",
+			first:     cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			next:      cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			wantCalls: 1,
+		},
+		{
+			name:      "other Authorization",
+			first:     cacheCall{headers: map[string]string{"Authorization": "Bearer a"}},
+			next:      cacheCall{headers: map[string]string{"Authorization": "Bearer b"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "other tenant",
+			first:     cacheCall{tenant: "acme"},
+			next:      cacheCall{tenant: "globex"},
+			wantCalls: 2,
+		},
+		{
+			name:      "other key header",
+			first:     cacheCall{headers: map[string]string{"X-Api-Key": "a"}},
+			next:      cacheCall{headers: map[string]string{"X-Api-Key": "b"}},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/items/1").Reply(http.StatusNotFound, "")
+			c := newNegativeCacheTestClient(mock)
+
+			tt.first.do(t, c, "/items/1")
+			tt.next.do(t, c, "/items/1")
+			if got := len(mock.Calls()); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNegativeCacheWriteInvalidatesEveryCaller(t *testing.T) {
+	mock := httpmock.New()
+	mock.On(http.MethodGet, "/items/1").Reply(http.StatusNotFound, "")
+	mock.On(http.MethodPut, "/items/1").Reply(http.StatusCreated, "")
+	c := newNegativeCacheTestClient(mock)
+
+	callers := []cacheCall{
+		{headers: map[string]string{"Authorization": "Bearer a"}},
+		{headers: map[string]string{"Authorization": "Bearer b"}},
+	}
+	for _, caller := range callers {
+		caller.do(t, c, "/items/1")
+	}
+	resp, err := c.Do(context.Background(), RequestOptions{Method: http.MethodPut, Path: "/items/1", Body: strings.NewReader("{}")})
+	if err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	resp.Body.Close()
+	for _, caller := range callers {
+		caller.do(t, c, "/items/1")
+	}
+
+	// Two lookups, the write, then two lookups again
+	if got := len(mock.Calls()); got != 5 {
+		t.Errorf("upstream calls = %d, want 5", got)
+	}
+}
@@ -0,0 +1,540 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"httpclient/utils"
+)
+
+// OutboxConfig configures an Outbox.
+type OutboxConfig struct {
+	// Path of the write-ahead log file; created if missing.
+	Path string
+	// RetryInterval is the initial wait after a failed delivery, doubling up
+	// to MaxRetryInterval (defaults 1s and 1m).
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// OnDrop is called for requests rejected with a non-retriable 4xx status.
+	// They are removed from the log so they do not block the queue forever.
+	// Rejections Do returns as errors, an *APIError with
+	// ClientConfig.ErrorOnNon2xx or a *PreconditionFailedError, are passed
+	// as a response with their status, headers and body.
+	OnDrop func(id string, resp *http.Response)
+	// OnRedirect is called for 3xx responses the client did not follow, e.g.
+	// with RedirectPolicy.NoFollow. Resending would get the same answer, so
+	// these requests are removed from the log as well.
+	OnRedirect func(id string, resp *http.Response)
+}
+
+// Outbox delivers mutating requests at least once across process restarts.
+// Enqueue persists a request to a write-ahead log before it is sent; Run sends
+// queued requests in order and marks them complete after a 2xx response.
+// Requests still pending when the process stops are resent by the next Run.
+// Every request carries its outbox ID as Idempotency-Key so servers can
+// deduplicate redeliveries.
+type Outbox struct {
+	client *CommonHTTPClient
+	cfg    OutboxConfig
+
+	mu      sync.Mutex
+	file    *os.File
+	pending []*outboxEntry
+	notify  chan struct{}
+}
+
+type outboxEntry struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Header    http.Header       `json:"header,omitempty"`
+	AddHeader http.Header       `json:"add_header,omitempty"`
+	Query     map[string]string `json:"query,omitempty"`
+	Body      []byte            `json:"body,omitempty"`
+	outboxOptions
+}
+
+// outboxOptions are the RequestOptions kept in the log besides the request
+// itself.
+type outboxOptions struct {
+	Endpoint                  string         `json:"endpoint,omitempty"`
+	RouteTemplate             string         `json:"route_template,omitempty"`
+	Priority                  Priority       `json:"priority,omitempty"`
+	Timeout                   time.Duration  `json:"timeout,omitempty"`
+	PerAttemptTimeout         time.Duration  `json:"per_attempt_timeout,omitempty"`
+	MaxRetries                int            `json:"max_retries,omitempty"`
+	RetryBackoff              time.Duration  `json:"retry_backoff,omitempty"`
+	IfMatch                   string         `json:"if_match,omitempty"`
+	IfNoneMatch               string         `json:"if_none_match,omitempty"`
+	AcceptLanguage            string         `json:"accept_language,omitempty"`
+	ExpectedContentTypes      []string       `json:"expected_content_types,omitempty"`
+	Cookies                   []*http.Cookie `json:"cookies,omitempty"`
+	Hedge                     bool           `json:"hedge,omitempty"`
+	DisableRequestCompression bool           `json:"disable_request_compression,omitempty"`
+	DisableQueryFallback      bool           `json:"disable_query_fallback,omitempty"`
+	DisableRedirects          bool           `json:"disable_redirects,omitempty"`
+	DisableLogBody            bool           `json:"disable_log_body,omitempty"`
+	DisableLogHeaders         bool           `json:"disable_log_headers,omitempty"`
+	DisableLogQuery           bool           `json:"disable_log_query,omitempty"`
+}
+
+// ErrOutboxUnsupported is returned by Enqueue for options that cannot be
+// stored in the log, such as Hooks or a Fallback.
+var ErrOutboxUnsupported = errors.New("httpclient: option cannot be stored in the outbox")
+
+// newOutboxOptions extracts the storable options of opts, failing on options
+// that are code or live state.
+func newOutboxOptions(opts RequestOptions) (outboxOptions, error) {
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"Hooks", opts.Hooks != nil},
+		{"Fallback", opts.Fallback != nil},
+		{"RetryPolicy", opts.RetryPolicy != nil},
+		{"QueryFallback", opts.QueryFallback != nil},
+		{"ProxyOverride", opts.ProxyOverride != nil},
+		{"StreamResponse", opts.StreamResponse},
+	} {
+		if option.set {
+			return outboxOptions{}, fmt.Errorf("%w: %s", ErrOutboxUnsupported, option.name)
+		}
+	}
+	return outboxOptions{
+		Endpoint:                  opts.Endpoint,
+		RouteTemplate:             opts.RouteTemplate,
+		Priority:                  opts.Priority,
+		Timeout:                   opts.Timeout,
+		PerAttemptTimeout:         opts.PerAttemptTimeout,
+		MaxRetries:                opts.MaxRetries,
+		RetryBackoff:              opts.RetryBackoff,
+		IfMatch:                   opts.IfMatch,
+		IfNoneMatch:               opts.IfNoneMatch,
+		AcceptLanguage:            opts.AcceptLanguage,
+		ExpectedContentTypes:      opts.ExpectedContentTypes,
+		Cookies:                   opts.Cookies,
+		Hedge:                     opts.Hedge,
+		DisableRequestCompression: opts.DisableRequestCompression,
+		DisableQueryFallback:      opts.DisableQueryFallback,
+		DisableRedirects:          opts.DisableRedirects,
+		DisableLogBody:            opts.DisableLogBody,
+		DisableLogHeaders:         opts.DisableLogHeaders,
+		DisableLogQuery:           opts.DisableLogQuery,
+	}, nil
+}
+
+// apply copies the stored options into opts.
+func (s outboxOptions) apply(opts *RequestOptions) {
+	opts.Endpoint = s.Endpoint
+	opts.RouteTemplate = s.RouteTemplate
+	opts.Priority = s.Priority
+	opts.Timeout = s.Timeout
+	opts.PerAttemptTimeout = s.PerAttemptTimeout
+	opts.MaxRetries = s.MaxRetries
+	opts.RetryBackoff = s.RetryBackoff
+	opts.IfMatch = s.IfMatch
+	opts.IfNoneMatch = s.IfNoneMatch
+	opts.AcceptLanguage = s.AcceptLanguage
+	opts.ExpectedContentTypes = s.ExpectedContentTypes
+	opts.Cookies = s.Cookies
+	opts.Hedge = s.Hedge
+	opts.DisableRequestCompression = s.DisableRequestCompression
+	opts.DisableQueryFallback = s.DisableQueryFallback
+	opts.DisableRedirects = s.DisableRedirects
+	opts.DisableLogBody = s.DisableLogBody
+	opts.DisableLogHeaders = s.DisableLogHeaders
+	opts.DisableLogQuery = s.DisableLogQuery
+}
+
+// outboxQuery merges Query and QueryParams as Do does. Repeated parameters
+// cannot be stored and fail.
+func outboxQuery(opts RequestOptions) (map[string]string, error) {
+	if opts.Query == nil {
+		return opts.QueryParams, nil
+	}
+	values, err := utils.EncodeQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	query := make(map[string]string, len(values)+len(opts.QueryParams))
+	for k, v := range values {
+		if len(v) > 1 {
+			return nil, fmt.Errorf("%w: repeated query parameter %q", ErrOutboxUnsupported, k)
+		}
+		query[k] = v[0]
+	}
+	for k, v := range opts.QueryParams {
+		query[k] = v
+	}
+	return query, nil
+}
+
+// walRecord is one line of the log: a queued request or a completion marker.
+type walRecord struct {
+	Op    string       `json:"op"`
+	ID    string       `json:"id"`
+	Entry *outboxEntry `json:"entry,omitempty"`
+}
+
+// OpenOutbox opens (or creates) the log at cfg.Path and recovers requests
+// that were accepted but not completed.
+func OpenOutbox(c *CommonHTTPClient, cfg OutboxConfig) (*Outbox, error) {
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	if cfg.MaxRetryInterval <= 0 {
+		cfg.MaxRetryInterval = time.Minute
+	}
+
+	pending, err := recoverOutbox(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Outbox{client: c, cfg: cfg, pending: pending, notify: make(chan struct{}, 1)}
+	if err := o.compact(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// recoverOutbox replays the log and returns queued entries without a
+// completion marker, in their original order. A torn final line from a crash
+// mid-write is ignored.
+func recoverOutbox(path string) ([]*outboxEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var order []*outboxEntry
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		switch rec.Op {
+		case "put":
+			if rec.Entry != nil {
+				order = append(order, rec.Entry)
+			}
+		case "done":
+			done[rec.ID] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	pending := order[:0]
+	for _, e := range order {
+		if !done[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+// compact rewrites the log with only pending entries and reopens it for appending.
+func (o *Outbox) compact() error {
+	tmp := o.cfg.Path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range o.pending {
+		if err := writeRecord(w, walRecord{Op: "put", ID: e.ID, Entry: e}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+	if err := os.Rename(tmp, o.cfg.Path); err != nil {
+		return err
+	}
+
+	if o.file != nil {
+		o.file.Close()
+	}
+	o.file, err = os.OpenFile(o.cfg.Path, os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+func writeRecord(w io.Writer, rec walRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// appendLocked durably appends a record to the log.
+func (o *Outbox) appendLocked(rec walRecord) error {
+	if o.file == nil {
+		return errors.New("outbox is closed")
+	}
+	if err := writeRecord(o.file, rec); err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
+
+// Enqueue durably records opts and returns its ID. The request is sent by Run.
+// PathParams and Query are expanded before the request is stored; options
+// that cannot be stored fail with ErrOutboxUnsupported.
+func (o *Outbox) Enqueue(opts RequestOptions) (string, error) {
+	if opts.PathParams != nil {
+		path, err := ExpandPath(opts.Path, opts.PathParams)
+		if err != nil {
+			return "", err
+		}
+		if opts.RouteTemplate == "" {
+			opts.RouteTemplate = opts.Path
+		}
+		opts.Path = path
+	}
+	query, err := outboxQuery(opts)
+	if err != nil {
+		return "", err
+	}
+	stored, err := newOutboxOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
+	var body []byte
+	if opts.GetBody != nil {
+		rc, err := opts.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		opts.Body = rc
+	}
+	if opts.Body != nil {
+		var err error
+		if body, err = io.ReadAll(opts.Body); err != nil {
+			return "", err
+		}
+	}
+
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	entry := &outboxEntry{
+		ID:        hex.EncodeToString(raw[:]),
+		Method:    opts.Method,
+		Path:      opts.Path,
+		Headers:   opts.Headers,
+		Header:    opts.Header,
+		AddHeader: opts.AddHeader,
+		Query:     query,
+		Body:      body,
+
+		outboxOptions: stored,
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.appendLocked(walRecord{Op: "put", ID: entry.ID, Entry: entry}); err != nil {
+		return "", err
+	}
+	o.pending = append(o.pending, entry)
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return entry.ID, nil
+}
+
+// Pending returns the number of requests not yet delivered.
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Run delivers queued requests in order until ctx is done.
+func (o *Outbox) Run(ctx context.Context) error {
+	backoff := o.cfg.RetryInterval
+	for {
+		o.mu.Lock()
+		var next *outboxEntry
+		if len(o.pending) > 0 {
+			next = o.pending[0]
+		}
+		o.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-o.notify:
+				continue
+			}
+		}
+
+		delivered, err := o.deliver(ctx, next)
+		if err != nil {
+			return err
+		}
+		if delivered {
+			backoff = o.cfg.RetryInterval
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, o.cfg.MaxRetryInterval)
+	}
+}
+
+// deliver sends one entry. It reports whether the entry left the queue; the
+// error is non-nil only when the log itself cannot be written.
+func (o *Outbox) deliver(ctx context.Context, e *outboxEntry) (bool, error) {
+	headers := make(map[string]string, len(e.Headers)+1)
+	headers["Idempotency-Key"] = e.ID
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+
+	opts := RequestOptions{
+		Method:      e.Method,
+		Path:        e.Path,
+		Headers:     headers,
+		Header:      e.Header,
+		AddHeader:   e.AddHeader,
+		QueryParams: e.Query,
+		Body:        bytes.NewReader(e.Body),
+	}
+	e.outboxOptions.apply(&opts)
+	resp, err := o.client.Do(ctx, opts)
+	if err != nil {
+		if resp = rejectedResponse(err); resp == nil || outboxOutcomeOf(resp.StatusCode) == outboxRetry {
+			o.client.logger.Warn("Outbox delivery failed", slog.String("id", e.ID), slog.Any("error", err))
+			return false, nil
+		}
+	}
+	resp.Body.Close()
+
+	switch outboxOutcomeOf(resp.StatusCode) {
+	case outboxRetry:
+		return false, nil
+	case outboxRedirected:
+		o.client.logger.Warn("Outbox request redirected", slog.String("id", e.ID),
+			slog.Int("status_code", resp.StatusCode), slog.String("location", resp.Header.Get("Location")))
+		if o.cfg.OnRedirect != nil {
+			o.cfg.OnRedirect(e.ID, resp)
+		}
+	case outboxDropped:
+		if o.cfg.OnDrop != nil {
+			o.cfg.OnDrop(e.ID, resp)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.appendLocked(walRecord{Op: "done", ID: e.ID}); err != nil {
+		return false, fmt.Errorf("outbox: mark %s done: %w", e.ID, err)
+	}
+	o.pending = o.pending[1:]
+	if len(o.pending) == 0 {
+		if err := o.compact(); err != nil {
+			return true, fmt.Errorf("outbox: compact: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// rejectedResponse rebuilds the response behind an error Do returns for a
+// status code, or returns nil for other errors.
+func rejectedResponse(err error) *http.Response {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return &http.Response{
+			StatusCode: apiErr.StatusCode,
+			Status:     fmt.Sprintf("%d %s", apiErr.StatusCode, http.StatusText(apiErr.StatusCode)),
+			Header:     apiErr.Header,
+			Body:       io.NopCloser(bytes.NewReader(apiErr.Body)),
+		}
+	case errors.Is(err, ErrPreconditionFailed):
+		return &http.Response{
+			StatusCode: http.StatusPreconditionFailed,
+			Status:     "412 " + http.StatusText(http.StatusPreconditionFailed),
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+		}
+	}
+	return nil
+}
+
+// outboxOutcome is what delivery does with an entry after a response.
+type outboxOutcome int
+
+const (
+	outboxDelivered outboxOutcome = iota
+	outboxRetry
+	outboxRedirected
+	outboxDropped
+)
+
+// outboxOutcomeOf classifies a response status.
+func outboxOutcomeOf(status int) outboxOutcome {
+	switch {
+	case status < 300:
+		return outboxDelivered
+	case status < 400:
+		return outboxRedirected
+	case status >= 500, status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return outboxRetry
+	default:
+		return outboxDropped
+	}
+}
+
+// Close closes the log. Pending requests are kept for the next OpenOutbox.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
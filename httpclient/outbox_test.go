@@ -0,0 +1,231 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestOutboxDeliverStatus(t *testing.T) {
+	tests := []struct {
+		status         int
+		wantDelivered  bool
+		wantDropped    bool
+		wantRedirected bool
+	}{
+		{status: http.StatusOK, wantDelivered: true},
+		{status: http.StatusNoContent, wantDelivered: true},
+		{status: http.StatusMovedPermanently, wantDelivered: true, wantRedirected: true},
+		{status: http.StatusNotModified, wantDelivered: true, wantRedirected: true},
+		{status: http.StatusPermanentRedirect, wantDelivered: true, wantRedirected: true},
+		{status: http.StatusBadRequest, wantDelivered: true, wantDropped: true},
+		{status: http.StatusConflict, wantDelivered: true, wantDropped: true},
+		{status: http.StatusRequestTimeout},
+		{status: http.StatusTooManyRequests},
+		{status: http.StatusInternalServerError},
+		{status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodPost, "/events").Reply(tt.status, "").Header("Location", "http://api.test/v2/events")
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:        base,
+				Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient:     &http.Client{Transport: mock},
+				RedirectPolicy: &RedirectPolicy{NoFollow: true},
+			})
+
+			var dropped, redirected bool
+			o, err := OpenOutbox(c, OutboxConfig{
+				Path:       filepath.Join(t.TempDir(), "outbox.log"),
+				OnDrop:     func(string, *http.Response) { dropped = true },
+				OnRedirect: func(string, *http.Response) { redirected = true },
+			})
+			if err != nil {
+				t.Fatalf("OpenOutbox: %v", err)
+			}
+			defer o.Close()
+			if _, err := o.Enqueue(RequestOptions{Method: http.MethodPost, Path: "/events", Body: strings.NewReader("{}")}); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+
+			delivered, err := o.deliver(context.Background(), o.pending[0])
+			if err != nil {
+				t.Fatalf("deliver: %v", err)
+			}
+			if delivered != tt.wantDelivered {
+				t.Errorf("delivered = %v, want %v", delivered, tt.wantDelivered)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("OnDrop called = %v, want %v", dropped, tt.wantDropped)
+			}
+			if redirected != tt.wantRedirected {
+				t.Errorf("OnRedirect called = %v, want %v", redirected, tt.wantRedirected)
+			}
+			wantPending := 1
+			if tt.wantDelivered {
+				wantPending = 0
+			}
+			if got := o.Pending(); got != wantPending {
+				t.Errorf("pending = %d, want %d", got, wantPending)
+			}
+		})
+	}
+}
+
+func TestOutboxDeliverRejectionErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		errorOnNon2xx bool
+		headers       map[string]string
+		wantDropped   int
+	}{
+		{name: "APIError 4xx", status: http.StatusBadRequest, errorOnNon2xx: true, wantDropped: http.StatusBadRequest},
+		{name: "APIError 5xx", status: http.StatusServiceUnavailable, errorOnNon2xx: true},
+		{name: "APIError 429", status: http.StatusTooManyRequests, errorOnNon2xx: true},
+		{
+			name:        "precondition failed",
+			status:      http.StatusPreconditionFailed,
+			headers:     map[string]string{"If-Match": `"v1"`},
+			wantDropped: http.StatusPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodPut, "/events/1").Reply(tt.status, "rejected")
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:       base,
+				Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient:    &http.Client{Transport: mock},
+				ErrorOnNon2xx: tt.errorOnNon2xx,
+			})
+
+			var dropped int
+			o, err := OpenOutbox(c, OutboxConfig{
+				Path:   filepath.Join(t.TempDir(), "outbox.log"),
+				OnDrop: func(_ string, resp *http.Response) { dropped = resp.StatusCode },
+			})
+			if err != nil {
+				t.Fatalf("OpenOutbox: %v", err)
+			}
+			defer o.Close()
+			if _, err := o.Enqueue(RequestOptions{Method: http.MethodPut, Path: "/events/1", Headers: tt.headers, Body: strings.NewReader("{}")}); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+
+			delivered, err := o.deliver(context.Background(), o.pending[0])
+			if err != nil {
+				t.Fatalf("deliver: %v", err)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("OnDrop status = %d, want %d", dropped, tt.wantDropped)
+			}
+			if want := tt.wantDropped != 0; delivered != want {
+				t.Errorf("left the queue = %v, want %v", delivered, want)
+			}
+		})
+	}
+}
+
+func TestOutboxEnqueueStoresExpandedRequest(t *testing.T) {
+	mock := httpmock.New()
+	mock.On(http.MethodPut, "/users/42").Reply(http.StatusOK, "")
+	base, _ := url.Parse("http://api.test")
+	c := NewCommonHTTPClient(ClientConfig{
+		BaseURL:    base,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HTTPClient: &http.Client{Transport: mock},
+	})
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	o, err := OpenOutbox(c, OutboxConfig{Path: path})
+	if err != nil {
+		t.Fatalf("OpenOutbox: %v", err)
+	}
+	_, err = o.Enqueue(RequestOptions{
+		Method:     http.MethodPut,
+		Path:       "/users/{id}",
+		PathParams: map[string]string{"id": "42"},
+		Query: struct {
+			Mode string `url:"mode"`
+		}{Mode: "merge"},
+		QueryParams: map[string]string{"dry_run": "false"},
+		Endpoint:    "users.update",
+		IfMatch:     `"v1"`,
+	})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	o.Close()
+
+	// Deliver from the log, as after a restart
+	o, err = OpenOutbox(c, OutboxConfig{Path: path})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer o.Close()
+	e := o.pending[0]
+	if e.RouteTemplate != "/users/{id}" || e.Endpoint != "users.update" {
+		t.Errorf("stored route %q, endpoint %q", e.RouteTemplate, e.Endpoint)
+	}
+	if _, err := o.deliver(context.Background(), e); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	calls := mock.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("upstream calls = %d, want 1", len(calls))
+	}
+	req := calls[0]
+	if got, want := req.URL.RequestURI(), "/users/42?dry_run=false&mode=merge"; got != want {
+		t.Errorf("request URI = %s, want %s", got, want)
+	}
+	if got := req.Header.Get("If-Match"); got != `"v1"` {
+		t.Errorf("If-Match = %q, want %q", got, `"v1"`)
+	}
+}
+
+func TestOutboxEnqueueRejectsUnstorableOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RequestOptions
+	}{
+		{name: "hooks", opts: RequestOptions{Hooks: &Hooks{}}},
+		{name: "fallback", opts: RequestOptions{Fallback: func(context.Context, *http.Request, error) (*http.Response, error) { return nil, nil }}},
+		{name: "retry policy", opts: RequestOptions{RetryPolicy: RetryPolicyFunc(func(*http.Response, error, int) (bool, time.Duration) { return false, 0 })}},
+		{name: "streamed response", opts: RequestOptions{StreamResponse: true}},
+		{name: "repeated query parameter", opts: RequestOptions{Query: struct {
+			IDs []int `url:"id"`
+		}{IDs: []int{1, 2}}}},
+	}
+	base, _ := url.Parse("http://api.test")
+	c := NewCommonHTTPClient(ClientConfig{BaseURL: base, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	o, err := OpenOutbox(c, OutboxConfig{Path: filepath.Join(t.TempDir(), "outbox.log")})
+	if err != nil {
+		t.Fatalf("OpenOutbox: %v", err)
+	}
+	defer o.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Method, tt.opts.Path = http.MethodPost, "/events"
+			if _, err := o.Enqueue(tt.opts); !errors.Is(err, ErrOutboxUnsupported) {
+				t.Errorf("Enqueue error = %v, want ErrOutboxUnsupported", err)
+			}
+		})
+	}
+	if got := o.Pending(); got != 0 {
+		t.Errorf("pending = %d, want 0", got)
+	}
+}
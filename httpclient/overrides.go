@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"context"
+	"time"
+)
+
+type overridesKey struct{}
+
+// requestOverrides carries the RequestOptions that replace client defaults
+// for one request.
+type requestOverrides struct {
+	maxRetries        int
+	retryBackoff      time.Duration
+	disableLogBody    bool
+	disableLogHeaders bool
+	disableLogQuery   bool
+}
+
+// withOverrides returns a context carrying the client defaults opts
+// overrides, if any.
+func withOverrides(ctx context.Context, opts RequestOptions) context.Context {
+	o := requestOverrides{
+		maxRetries:        opts.MaxRetries,
+		retryBackoff:      opts.RetryBackoff,
+		disableLogBody:    opts.DisableLogBody,
+		disableLogHeaders: opts.DisableLogHeaders,
+		disableLogQuery:   opts.DisableLogQuery,
+	}
+	if o == (requestOverrides{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+func overridesFrom(ctx context.Context) requestOverrides {
+	o, _ := ctx.Value(overridesKey{}).(requestOverrides)
+	return o
+}
+
+// logged reports which parts of a request made with ctx are logged. full is
+// set for hosts with escalated logging, which ignore the client's settings but
+// not the request's own opt-outs.
+func (c *CommonHTTPClient) logged(ctx context.Context, full bool) (body, headers, query bool) {
+	o := overridesFrom(ctx)
+	if full {
+		return !o.disableLogBody, !o.disableLogHeaders, !o.disableLogQuery
+	}
+	return !c.disableLogBody && !o.disableLogBody,
+		!c.disableLogHeaders && !o.disableLogHeaders,
+		!c.disableLogQuery && !o.disableLogQuery
+}
@@ -0,0 +1,254 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNoMorePages is returned by PageIterator.Next after the last page.
+var ErrNoMorePages = errors.New("no more pages")
+
+// PageIterator walks the pages of a Paginator one request at a time.
+// Checkpoints are not consulted; use FetchAll for resumable bulk syncs.
+type PageIterator[T any] struct {
+	client *CommonHTTPClient
+	pages  Paginator[T]
+	cursor string
+	done   bool
+	links  Links
+}
+
+// NewPageIterator returns an iterator over p using c (the default client when
+// nil), starting at the first page.
+func NewPageIterator[T any](c *CommonHTTPClient, p Paginator[T]) *PageIterator[T] {
+	if c == nil {
+		c = DefaultClient()
+	}
+	it := &PageIterator[T]{client: c, pages: p}
+	parse := p.Parse
+	it.pages.Parse = func(resp *http.Response) ([]T, string, error) {
+		it.links = ParseLinks(resp)
+		return parse(resp)
+	}
+	return it
+}
+
+// Next fetches and decodes the next page. After the last page it returns
+// ErrNoMorePages. A page that failed is fetched again by the next call.
+func (it *PageIterator[T]) Next(ctx context.Context) ([]T, error) {
+	if it.done {
+		return nil, ErrNoMorePages
+	}
+	items, next, err := fetchPage(ctx, it.client, it.pages, it.cursor)
+	if err != nil {
+		return nil, err
+	}
+	it.cursor = next
+	it.done = next == ""
+	return items, nil
+}
+
+// Cursor returns the cursor of the page the next call to Next fetches.
+func (it *PageIterator[T]) Cursor() string {
+	return it.cursor
+}
+
+// Links returns the Link headers of the page fetched last, e.g. to read the
+// Last page URL of a GitHub-style listing.
+func (it *PageIterator[T]) Links() Links {
+	return it.links
+}
+
+// All returns a sequence of every item on the remaining pages. A failure is
+// yielded once as the zero T with a non-nil error, ending the sequence.
+func (it *PageIterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			items, err := it.Next(ctx)
+			if errors.Is(err, ErrNoMorePages) {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// The page strategies below decode JSON pages. itemsField locates the items:
+// "" for a top-level array, otherwise a dot-separated field path such as
+// "data.items".
+
+// LinkPages returns a Paginator that follows Link rel="next" headers
+// (RFC 8288). The cursor is the next page URL, so the query of opts only
+// applies to the first page.
+func LinkPages[T any](opts RequestOptions, itemsField string) Paginator[T] {
+	return Paginator[T]{
+		Request: func(cursor string) RequestOptions {
+			if cursor == "" {
+				return opts
+			}
+			next := opts
+			if next.RouteTemplate == "" && opts.PathParams != nil {
+				next.RouteTemplate = opts.Path
+			}
+			next.Path, next.PathParams, next.Query, next.QueryParams = cursor, nil, nil, nil
+			return next
+		},
+		Parse: func(resp *http.Response) ([]T, string, error) {
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, "", err
+			}
+			items, err := decodePageItems[T](data, itemsField)
+			if err != nil {
+				return nil, "", err
+			}
+			return items, ParseLinks(resp).Next, nil
+		},
+	}
+}
+
+// CursorPages returns a Paginator for APIs that return the next cursor in
+// the JSON body at cursorField and accept it in the cursorParam query
+// parameter. A missing, null or empty cursor ends the listing.
+func CursorPages[T any](opts RequestOptions, itemsField, cursorField, cursorParam string) Paginator[T] {
+	return Paginator[T]{
+		Request: func(cursor string) RequestOptions {
+			if cursor == "" {
+				return opts
+			}
+			return withQueryParams(opts, map[string]string{cursorParam: cursor})
+		},
+		Parse: func(resp *http.Response) ([]T, string, error) {
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, "", err
+			}
+			items, err := decodePageItems[T](data, itemsField)
+			if err != nil {
+				return nil, "", err
+			}
+			raw, err := lookupJSON(data, cursorField)
+			if err != nil {
+				return nil, "", err
+			}
+			next, err := cursorString(raw)
+			if err != nil {
+				return nil, "", fmt.Errorf("cursor field %q: %w", cursorField, err)
+			}
+			return items, next, nil
+		},
+	}
+}
+
+// OffsetPages returns a Paginator sending offsetParam and limitParam query
+// parameters. The listing ends with the first page holding fewer than limit
+// items.
+func OffsetPages[T any](opts RequestOptions, itemsField, offsetParam, limitParam string, limit int) Paginator[T] {
+	return Paginator[T]{
+		Request: func(cursor string) RequestOptions {
+			if cursor == "" {
+				cursor = "0"
+			}
+			return withQueryParams(opts, map[string]string{offsetParam: cursor, limitParam: strconv.Itoa(limit)})
+		},
+		Parse: func(resp *http.Response) ([]T, string, error) {
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, "", err
+			}
+			items, err := decodePageItems[T](data, itemsField)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(items) < limit || len(items) == 0 {
+				return items, "", nil
+			}
+			offset, _ := strconv.Atoi(resp.Request.URL.Query().Get(offsetParam))
+			return items, strconv.Itoa(offset + len(items)), nil
+		},
+	}
+}
+
+// withQueryParams returns opts with extra merged over a copy of its QueryParams.
+func withQueryParams(opts RequestOptions, extra map[string]string) RequestOptions {
+	query := make(map[string]string, len(opts.QueryParams)+len(extra))
+	for k, v := range opts.QueryParams {
+		query[k] = v
+	}
+	for k, v := range extra {
+		query[k] = v
+	}
+	opts.QueryParams = query
+	return opts
+}
+
+// decodePageItems decodes the items of a JSON page located by field.
+func decodePageItems[T any](data []byte, field string) ([]T, error) {
+	raw, err := lookupJSON(data, field)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return items, nil
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decode page items: %w", err)
+	}
+	return items, nil
+}
+
+// lookupJSON returns the value at the dot-separated path in data, or nil when
+// a field is missing. An empty path returns data itself.
+func lookupJSON(data []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(data)
+	if path == "" {
+		return raw, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			return nil, nil
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("decode page field %q: %w", key, err)
+		}
+		raw = obj[key]
+	}
+	return raw, nil
+}
+
+// cursorString renders a JSON string or number cursor; null yields "".
+func cursorString(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
@@ -0,0 +1,226 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Paginator describes a cursor-paginated listing. Offset pagination fits too:
+// encode the offset as the cursor with strconv. LinkPages, CursorPages and
+// OffsetPages build Paginators for common JSON APIs.
+type Paginator[T any] struct {
+	// Request returns the request for the page at cursor; the first page has
+	// cursor "".
+	Request func(cursor string) RequestOptions
+	// Parse decodes one page, returning its items and the cursor of the next
+	// page, or "" after the last page. Parse need not close the body.
+	Parse func(resp *http.Response) (items []T, next string, err error)
+	// Checkpoints, when set, persists the cursor of the next page under
+	// CheckpointKey after every handled page, so an interrupted FetchAll
+	// resumes where it left off. The checkpoint is deleted on completion.
+	Checkpoints   CheckpointStore
+	CheckpointKey string
+}
+
+// CheckpointStore persists pagination cursors. Implementations must be safe
+// for concurrent use.
+type CheckpointStore interface {
+	// Load returns the saved cursor for key; ok is false when there is none.
+	Load(ctx context.Context, key string) (cursor string, ok bool, err error)
+	Save(ctx context.Context, key, cursor string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// FetchAll walks every page of p with c (the default client when nil) and
+// passes each page's items to handle. Pages are not accumulated, so long bulk
+// syncs run in constant memory. A page is checkpointed only after handle
+// returns nil; on error FetchAll stops and the next run resumes at that page.
+func FetchAll[T any](ctx context.Context, c *CommonHTTPClient, p Paginator[T], handle func(items []T) error) error {
+	if c == nil {
+		c = DefaultClient()
+	}
+
+	cursor := ""
+	if p.Checkpoints != nil {
+		saved, ok, err := p.Checkpoints.Load(ctx, p.CheckpointKey)
+		if err != nil {
+			return fmt.Errorf("load checkpoint: %w", err)
+		}
+		if ok {
+			cursor = saved
+		}
+	}
+
+	for {
+		items, next, err := fetchPage(ctx, c, p, cursor)
+		if err != nil {
+			return err
+		}
+		if err := handle(items); err != nil {
+			return err
+		}
+		if next == "" {
+			break
+		}
+		if p.Checkpoints != nil {
+			if err := p.Checkpoints.Save(ctx, p.CheckpointKey, next); err != nil {
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+		}
+		cursor = next
+	}
+
+	if p.Checkpoints != nil {
+		if err := p.Checkpoints.Delete(ctx, p.CheckpointKey); err != nil {
+			return fmt.Errorf("delete checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// fetchPage requests and parses the page of p at cursor.
+func fetchPage[T any](ctx context.Context, c *CommonHTTPClient, p Paginator[T], cursor string) ([]T, string, error) {
+	resp, err := c.Do(ctx, p.Request(cursor))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, "", c.newAPIError(resp)
+	}
+	defer resp.Body.Close()
+	items, next, err := p.Parse(resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse page: %w", err)
+	}
+	return items, next, nil
+}
+
+// MemoryCheckpoints is an in-process CheckpointStore, useful for retrying a
+// sync within one run and for tests.
+type MemoryCheckpoints struct {
+	mu      sync.Mutex
+	cursors map[string]string
+}
+
+// Load implements CheckpointStore.
+func (m *MemoryCheckpoints) Load(_ context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cursor, ok := m.cursors[key]
+	return cursor, ok, nil
+}
+
+// Save implements CheckpointStore.
+func (m *MemoryCheckpoints) Save(_ context.Context, key, cursor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cursors == nil {
+		m.cursors = make(map[string]string)
+	}
+	m.cursors[key] = cursor
+	return nil
+}
+
+// Delete implements CheckpointStore.
+func (m *MemoryCheckpoints) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cursors, key)
+	return nil
+}
+
+// FileCheckpoints is a CheckpointStore keeping all cursors in one JSON file,
+// replaced atomically on every save so a crash never leaves it torn.
+type FileCheckpoints struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Load implements CheckpointStore.
+func (f *FileCheckpoints) Load(_ context.Context, key string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return "", false, err
+	}
+	cursor, ok := cursors[key]
+	return cursor, ok, nil
+}
+
+// Save implements CheckpointStore.
+func (f *FileCheckpoints) Save(_ context.Context, key, cursor string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return err
+	}
+	cursors[key] = cursor
+	return f.write(cursors)
+}
+
+// Delete implements CheckpointStore.
+func (f *FileCheckpoints) Delete(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := cursors[key]; !ok {
+		return nil
+	}
+	delete(cursors, key)
+	return f.write(cursors)
+}
+
+func (f *FileCheckpoints) read() (map[string]string, error) {
+	cursors := make(map[string]string)
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("checkpoint file %s: %w", f.Path, err)
+	}
+	return cursors, nil
+}
+
+// write stores cursors through a temporary file renamed into place.
+func (f *FileCheckpoints) write(cursors map[string]string) error {
+	data, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
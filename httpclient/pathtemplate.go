@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExpandPath substitutes "{name}" placeholders in template with params,
+// escaping each value as a single path segment: "/" becomes %2F, and "." and
+// ".." cannot climb out of the route. Every placeholder must have a value and
+// every value a placeholder.
+func ExpandPath(template string, params map[string]string) (string, error) {
+	var b strings.Builder
+	used := 0
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("path template %q: unclosed placeholder", template)
+		}
+		name := rest[open+1 : open+end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("path template %q: missing parameter %q", template, name)
+		}
+		if value == "" {
+			return "", fmt.Errorf("path template %q: empty parameter %q", template, name)
+		}
+		b.WriteString(rest[:open])
+		b.WriteString(escapePathParam(value))
+		used++
+		rest = rest[open+end+1:]
+	}
+	if used < len(params) {
+		for name := range params {
+			if !strings.Contains(template, "{"+name+"}") {
+				return "", fmt.Errorf("path template %q: unknown parameter %q", template, name)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// escapePathParam escapes value as one path segment. Dot segments are
+// percent-encoded so path cleaning cannot resolve them.
+func escapePathParam(value string) string {
+	switch value {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	}
+	return url.PathEscape(value)
+}
@@ -0,0 +1,212 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// PollerConfig configures a Poller. Exactly one of Interval or Cron is required.
+type PollerConfig struct {
+	// Request is issued on every tick. Its Body must be nil, since a reader
+	// cannot be replayed across polls.
+	Request RequestOptions
+	// Interval between polls.
+	Interval time.Duration
+	// Cron is a five-field cron expression evaluated in local time.
+	Cron string
+	// Jitter adds a random delay in [0, Jitter) to every wait.
+	Jitter time.Duration
+	// MaxBackoff caps the delay after consecutive failures, which doubles from
+	// Interval (or one minute for cron schedules). Defaults to 10 minutes.
+	MaxBackoff time.Duration
+	// OnlyChanges suppresses results whose content did not change.
+	OnlyChanges bool
+	// OnResult, if set, receives results instead of the Results channel.
+	OnResult func(PollResult)
+}
+
+// PollResult is delivered after every poll (or every change with OnlyChanges).
+type PollResult struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	ETag       string
+	// Changed is true when the content differs from the previous successful poll.
+	Changed   bool
+	Err       error
+	FetchedAt time.Time
+}
+
+// Poller runs a request on a schedule, for config and feature-flag polling.
+type Poller struct {
+	client   *CommonHTTPClient
+	cfg      PollerConfig
+	schedule *cronSchedule
+	results  chan PollResult
+
+	etag     string
+	digest   [sha256.Size]byte
+	hasValue bool
+	failures int
+}
+
+// NewPoller creates a Poller that issues cfg.Request through c.
+func (c *CommonHTTPClient) NewPoller(cfg PollerConfig) (*Poller, error) {
+	if (cfg.Interval > 0) == (cfg.Cron != "") {
+		return nil, errors.New("poller: exactly one of Interval or Cron must be set")
+	}
+	if cfg.Request.Body != nil {
+		return nil, errors.New("poller: request body is not supported")
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 10 * time.Minute
+	}
+
+	p := &Poller{client: c, cfg: cfg}
+	if cfg.Cron != "" {
+		schedule, err := parseCron(cfg.Cron)
+		if err != nil {
+			return nil, err
+		}
+		p.schedule = schedule
+	}
+	if cfg.OnResult == nil {
+		p.results = make(chan PollResult, 1)
+	}
+	return p, nil
+}
+
+// Results returns the channel results are delivered on. It is nil when
+// OnResult is configured and is closed when Run returns.
+func (p *Poller) Results() <-chan PollResult {
+	return p.results
+}
+
+// Run polls until ctx is done. Interval schedules poll immediately on start;
+// cron schedules wait for the first matching minute.
+func (p *Poller) Run(ctx context.Context) error {
+	if p.results != nil {
+		defer close(p.results)
+	}
+
+	wait := time.Duration(0)
+	if p.schedule != nil {
+		wait = p.nextDelay()
+	}
+	for {
+		timer := time.NewTimer(wait + p.jitter())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		result := p.poll(ctx)
+		if result.Err != nil {
+			p.failures++
+		} else {
+			p.failures = 0
+		}
+		if result.Err != nil || result.Changed || !p.cfg.OnlyChanges {
+			if !p.deliver(ctx, result) {
+				return ctx.Err()
+			}
+		}
+		wait = p.nextDelay()
+	}
+}
+
+// poll performs one conditional request and updates the change state.
+func (p *Poller) poll(ctx context.Context) PollResult {
+	opts := p.cfg.Request
+	opts.Headers = make(map[string]string, len(p.cfg.Request.Headers)+1)
+	for k, v := range p.cfg.Request.Headers {
+		opts.Headers[k] = v
+	}
+	if p.etag != "" {
+		opts.Headers["If-None-Match"] = p.etag
+	}
+
+	result := PollResult{FetchedAt: time.Now()}
+	resp, err := p.client.Do(ctx, opts)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Header = resp.Header
+	if resp.StatusCode == http.StatusNotModified {
+		result.ETag = p.etag
+		return result
+	}
+	if resp.StatusCode >= 400 {
+		result.Err = fmt.Errorf("poller: unexpected status %d", resp.StatusCode)
+		return result
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Body = body
+	result.ETag = resp.Header.Get("ETag")
+
+	digest := sha256.Sum256(body)
+	result.Changed = !p.hasValue || !bytes.Equal(digest[:], p.digest[:])
+	p.digest, p.etag, p.hasValue = digest, result.ETag, true
+	return result
+}
+
+func (p *Poller) deliver(ctx context.Context, result PollResult) bool {
+	if p.cfg.OnResult != nil {
+		p.cfg.OnResult(result)
+		return true
+	}
+	select {
+	case p.results <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nextDelay returns the wait before the next poll, backing off on failures.
+func (p *Poller) nextDelay() time.Duration {
+	if p.failures > 0 {
+		base := p.cfg.Interval
+		if base <= 0 {
+			base = time.Minute
+		}
+		backoff := base << min(p.failures, 20)
+		if backoff <= 0 || backoff > p.cfg.MaxBackoff {
+			backoff = p.cfg.MaxBackoff
+		}
+		return backoff
+	}
+	if p.schedule != nil {
+		next := p.schedule.next(time.Now())
+		if next.IsZero() {
+			return p.cfg.MaxBackoff
+		}
+		return time.Until(next)
+	}
+	return p.cfg.Interval
+}
+
+func (p *Poller) jitter() time.Duration {
+	if p.cfg.Jitter <= 0 {
+		return 0
+	}
+	return rand.N(p.cfg.Jitter)
+}
@@ -0,0 +1,197 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Priority classifies a request for the Scheduler. The zero value is normal.
+type Priority int
+
+const (
+	PriorityBackground Priority = -1
+	PriorityNormal     Priority = 0
+	PriorityCritical   Priority = 1
+)
+
+// ErrOverloaded is returned when a request is shed instead of being queued.
+var ErrOverloaded = errors.New("httpclient: request shed due to overload")
+
+type priorityKey struct{}
+
+// WithPriority returns a context carrying p, read by the client's Scheduler.
+// RequestOptions.Priority is a shortcut for the same thing.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority stored in ctx, or PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// SchedulerConfig configures a Scheduler.
+type SchedulerConfig struct {
+	// MaxConcurrent is the number of requests allowed in flight (default 1).
+	MaxConcurrent int
+	// MaxQueue bounds the number of waiting requests; 0 means unbounded.
+	// When full, non-critical requests are shed with ErrOverloaded.
+	MaxQueue int
+	// ShedBackgroundAt sheds background requests once this many requests are
+	// waiting; 0 disables depth-based shedding.
+	ShedBackgroundAt int
+	// Pressure is an optional external signal (e.g. an open breaker or a
+	// saturated limiter); while it reports true, background requests are shed.
+	Pressure func() bool
+	// ShedBackgroundWhenBusy sheds background requests instead of queueing
+	// them while all MaxConcurrent slots are in use.
+	ShedBackgroundWhenBusy bool
+	// MaxQueueWait, if > 0, sheds non-critical requests that waited this long
+	// without getting a slot.
+	MaxQueueWait time.Duration
+}
+
+// Scheduler admits requests under a concurrency limit, dispatching waiting
+// requests strictly by priority and FIFO within a priority class.
+type Scheduler struct {
+	mu       sync.Mutex
+	cfg      SchedulerConfig
+	inFlight int
+	// queues is indexed by priority - PriorityBackground
+	queues [3][]chan struct{}
+	shed   atomic.Int64
+}
+
+// NewScheduler creates a Scheduler with the provided config.
+func NewScheduler(cfg SchedulerConfig) *Scheduler {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	return &Scheduler{cfg: cfg}
+}
+
+// Acquire blocks until a slot is available for a request of priority p, the
+// request is shed, or ctx is done. Every successful Acquire must be paired
+// with a call to Release.
+func (s *Scheduler) Acquire(ctx context.Context, p Priority) error {
+	idx := queueIndex(p)
+
+	s.mu.Lock()
+	queued := s.queuedLocked()
+	if p == PriorityBackground && s.shedBackgroundLocked(queued) {
+		s.mu.Unlock()
+		return s.shedRequest()
+	}
+	if s.inFlight < s.cfg.MaxConcurrent && queued == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return nil
+	}
+	if p != PriorityCritical && s.cfg.MaxQueue > 0 && queued >= s.cfg.MaxQueue {
+		s.mu.Unlock()
+		return s.shedRequest()
+	}
+	if p == PriorityBackground && s.cfg.ShedBackgroundWhenBusy {
+		s.mu.Unlock()
+		return s.shedRequest()
+	}
+	ready := make(chan struct{})
+	s.queues[idx] = append(s.queues[idx], ready)
+	s.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if s.cfg.MaxQueueWait > 0 && p != PriorityCritical {
+		timer := time.NewTimer(s.cfg.MaxQueueWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return s.abandon(idx, ready, ctx.Err())
+	case <-timeout:
+		return s.abandon(idx, ready, s.shedRequest())
+	}
+}
+
+// abandon removes a waiter that gave up, returning err. A slot handed over
+// concurrently is given back.
+func (s *Scheduler) abandon(idx int, ready chan struct{}, err error) error {
+	s.mu.Lock()
+	for i, w := range s.queues[idx] {
+		if w == ready {
+			s.queues[idx] = append(s.queues[idx][:i], s.queues[idx][i+1:]...)
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+	s.Release()
+	return err
+}
+
+// shedRequest counts a shed request and returns ErrOverloaded.
+func (s *Scheduler) shedRequest() error {
+	s.shed.Add(1)
+	return ErrOverloaded
+}
+
+// Release frees a slot, handing it directly to the highest-priority waiter.
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.queues) - 1; i >= 0; i-- {
+		if len(s.queues[i]) > 0 {
+			next := s.queues[i][0]
+			s.queues[i] = s.queues[i][1:]
+			close(next)
+			return
+		}
+	}
+	s.inFlight--
+}
+
+// Stats returns the current in-flight and queued request counts.
+func (s *Scheduler) Stats() (inFlight, queued int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight, s.queuedLocked()
+}
+
+// Shed returns how many requests were shed with ErrOverloaded so far.
+func (s *Scheduler) Shed() int64 {
+	return s.shed.Load()
+}
+
+func (s *Scheduler) queuedLocked() int {
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (s *Scheduler) shedBackgroundLocked(queued int) bool {
+	if s.cfg.ShedBackgroundAt > 0 && queued >= s.cfg.ShedBackgroundAt {
+		return true
+	}
+	return s.cfg.Pressure != nil && s.cfg.Pressure()
+}
+
+func queueIndex(p Priority) int {
+	switch {
+	case p <= PriorityBackground:
+		return 0
+	case p >= PriorityCritical:
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ErrProxyOverrideBlocked is returned for RequestOptions.ProxyOverride when
+// SSRF protection is enabled, as the proxy would dial on the client's behalf.
+var ErrProxyOverrideBlocked = errors.New("httpclient: proxy override not allowed with SSRF protection")
+
+type proxyKey struct{}
+
+// withProxy returns a context routing the request through proxy.
+func withProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+func proxyFromContext(ctx context.Context) *url.URL {
+	proxy, _ := ctx.Value(proxyKey{}).(*url.URL)
+	return proxy
+}
+
+// proxyClients caches one client per override proxy, so each egress keeps its
+// own connection pool.
+type proxyClients struct {
+	allowed bool
+	mu      sync.Mutex
+	clients map[string]*http.Client
+}
+
+func newProxyClients(allowed bool) *proxyClients {
+	return &proxyClients{allowed: allowed, clients: make(map[string]*http.Client)}
+}
+
+// clientFor returns the client sending req: c.client, or a derived client
+// when the request overrides the proxy.
+func (c *CommonHTTPClient) clientFor(req *http.Request) (*http.Client, error) {
+	proxy := proxyFromContext(req.Context())
+	if proxy == nil {
+		return c.client, nil
+	}
+	if !c.proxies.allowed {
+		return nil, ErrProxyOverrideBlocked
+	}
+
+	key := proxy.String()
+	c.proxies.mu.Lock()
+	defer c.proxies.mu.Unlock()
+	if client, ok := c.proxies.clients[key]; ok {
+		return client, nil
+	}
+	client, err := withTransport(c.client, c.logger, func(t *http.Transport) {
+		t.Proxy = http.ProxyURL(proxy)
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	if client == c.client {
+		c.logger.Warn("Ignoring proxy override", slog.String("proxy", c.redactor.URL(proxy)))
+	}
+	c.proxies.clients[key] = client
+	return client, nil
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// RateLimit declares a token-bucket limit for one endpoint. A request uses the
+// first profile whose Name equals its endpoint name (see WithEndpoint) or whose
+// Pattern matches its URL path using path.Match syntax, e.g. "/users/*/posts".
+// A profile with Pattern "*" and no Name acts as a catch-all.
+type RateLimit struct {
+	Name    string
+	Pattern string
+	// RPS is the sustained number of requests per second.
+	RPS float64
+	// Burst is the bucket size (default 1).
+	Burst int
+}
+
+type endpointKey struct{}
+
+// WithEndpoint returns a context naming the logical endpoint of a request,
+// used to select named rate limit profiles. RequestOptions.Endpoint is a
+// shortcut for the same thing.
+func WithEndpoint(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, name)
+}
+
+// EndpointFromContext returns the endpoint name stored in ctx, if any.
+func EndpointFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(endpointKey{}).(string)
+	return name
+}
+
+// rateLimiter holds one independent token bucket per profile.
+type rateLimiter struct {
+	profiles []RateLimit
+	buckets  []*tokenBucket
+}
+
+func newRateLimiter(profiles []RateLimit) *rateLimiter {
+	if len(profiles) == 0 {
+		return nil
+	}
+	l := &rateLimiter{profiles: profiles}
+	for _, p := range profiles {
+		l.buckets = append(l.buckets, newTokenBucket(p.RPS, p.Burst))
+	}
+	return l
+}
+
+// wait blocks until the profile matching req has a token, or ctx is done.
+// Requests matching no profile are not limited.
+func (l *rateLimiter) wait(req *http.Request) error {
+	if l == nil {
+		return nil
+	}
+	endpoint := EndpointFromContext(req.Context())
+	for i, p := range l.profiles {
+		if endpoint != "" && p.Name == endpoint {
+			return l.buckets[i].wait(req.Context())
+		}
+	}
+	for i, p := range l.profiles {
+		if p.Pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(p.Pattern, req.URL.Path); ok {
+			return l.buckets[i].wait(req.Context())
+		}
+	}
+	return nil
+}
+
+// tokenBucket is a minimal reservation-based token bucket.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Return the reserved token so cancelled callers do not starve others
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
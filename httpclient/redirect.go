@@ -0,0 +1,111 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrTooManyRedirects is returned when a request exceeds
+// RedirectPolicy.MaxRedirects.
+var ErrTooManyRedirects = errors.New("httpclient: too many redirects")
+
+// ErrCrossHostRedirect is returned when RedirectPolicy.DenyCrossHost rejects
+// a redirect to another host.
+var ErrCrossHostRedirect = errors.New("httpclient: redirect to another host")
+
+// RedirectPolicy controls how the client follows redirects. Every followed
+// hop is logged at the request log level.
+type RedirectPolicy struct {
+	// MaxRedirects bounds the hops followed per request (default 10).
+	MaxRedirects int
+	// DenyCrossHost fails redirects to a host other than the one originally
+	// requested with ErrCrossHostRedirect.
+	DenyCrossHost bool
+	// StripAuthorization removes the Authorization and Cookie headers when a
+	// redirect changes the scheme, host or port. net/http only drops them
+	// for other domains. Cookies from the cookie jar are still sent to the
+	// hosts they belong to.
+	StripAuthorization bool
+	// NoFollow returns 3xx responses to the caller instead of following
+	// them, as RequestOptions.DisableRedirects does per request.
+	NoFollow bool
+}
+
+type noRedirectsKey struct{}
+
+// withoutRedirects marks a request context so redirects are not followed.
+func withoutRedirects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRedirectsKey{}, true)
+}
+
+func redirectsDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRedirectsKey{}).(bool)
+	return disabled
+}
+
+// withRedirectPolicy applies p, which may be nil, and the host policy to the
+// redirects followed by hc, chaining any existing CheckRedirect. It returns a
+// copy so the caller's client is kept.
+func (c *CommonHTTPClient) withRedirectPolicy(hc *http.Client, p *RedirectPolicy) *http.Client {
+	if p == nil {
+		p = &RedirectPolicy{}
+	}
+	maxRedirects := p.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = 10
+	}
+	clone := *hc
+	next := hc.CheckRedirect
+	clone.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if p.NoFollow || redirectsDisabled(req.Context()) {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, maxRedirects)
+		}
+		if c.hostPolicy != nil {
+			if err := c.hostPolicy.Check(req.URL.Hostname()); err != nil {
+				return err
+			}
+		}
+		original := via[0].URL
+		if p.DenyCrossHost && !strings.EqualFold(req.URL.Hostname(), original.Hostname()) {
+			return fmt.Errorf("%w: %s to %s", ErrCrossHostRedirect, original.Hostname(), req.URL.Hostname())
+		}
+		if p.StripAuthorization && (req.URL.Scheme != original.Scheme || !strings.EqualFold(req.URL.Host, original.Host)) {
+			req.Header.Del("Authorization")
+			req.Header.Del("Cookie")
+		}
+		if next != nil {
+			if err := next(req, via); err != nil {
+				return err
+			}
+		}
+		c.logRedirect(req, via)
+		return nil
+	}
+	return &clone
+}
+
+// logRedirect logs a followed redirect hop.
+func (c *CommonHTTPClient) logRedirect(req *http.Request, via []*http.Request) {
+	level := c.logLevels.Request.Level()
+	if c.escalator.escalated(req.URL.Host) {
+		level = max(level, slog.LevelInfo)
+	}
+	if !c.logger.Enabled(req.Context(), level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("from", c.redactor.URL(via[len(via)-1].URL)),
+		slog.Int("hop", len(via)),
+	}
+	if req.Response != nil {
+		attrs = append(attrs, slog.Int("status_code", req.Response.StatusCode))
+	}
+	c.logEvent(req.Context(), level, "Following redirect", c.newLogEvent(req, 0, 0), attrs...)
+}
@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestBodyReplayedWithoutRetries(t *testing.T) {
+	const payload = `{"name":"widget"}`
+	tests := []struct {
+		name string
+		cfg  ClientConfig
+		opts RequestOptions
+		// delay holds the first call back so a hedge is sent
+		delay time.Duration
+	}{
+		{
+			name: "auth refresh",
+			cfg: ClientConfig{
+				RefreshAuth: func(context.Context) (string, error) { return "Bearer new", nil },
+			},
+		},
+		{
+			name:  "hedged duplicate",
+			cfg:   ClientConfig{Hedging: &HedgeConfig{Delay: 5 * time.Millisecond}},
+			opts:  RequestOptions{Hedge: true},
+			delay: 200 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []string
+			var replies atomic.Int32
+			mock := httpmock.New()
+			// The mock consumes the body before replying; record it while matching
+			record := func(_ *http.Request, body []byte) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				bodies = append(bodies, string(body))
+				return true
+			}
+			mock.On(http.MethodPost, "/items", record).ReplyFunc(func(req *http.Request) (*http.Response, error) {
+				first := replies.Add(1) == 1
+				status := http.StatusCreated
+				if first && tt.cfg.RefreshAuth != nil {
+					status = http.StatusUnauthorized
+				}
+				if first && tt.delay > 0 {
+					select {
+					case <-time.After(tt.delay):
+					case <-req.Context().Done():
+						return nil, req.Context().Err()
+					}
+				}
+				return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+			})
+			cfg := tt.cfg
+			cfg.BaseURL, _ = url.Parse("http://api.test")
+			cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg.HTTPClient = &http.Client{Transport: mock}
+			c := NewCommonHTTPClient(cfg)
+
+			opts := tt.opts
+			opts.Method, opts.Path = http.MethodPost, "/items"
+			// A reader net/http cannot rewind by itself
+			opts.Body = io.MultiReader(strings.NewReader(payload))
+			resp, err := c.Do(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(bodies) != 2 {
+				t.Fatalf("upstream calls = %d, want 2", len(bodies))
+			}
+			for i, body := range bodies {
+				if body != payload {
+					t.Errorf("call %d body = %q, want %q", i+1, body, payload)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressBody gzips body when it has at least compressOver bytes. It
+// returns the body to send and whether it was compressed. Bodies that already
+// declare a Content-Encoding are left alone.
+func (c *CommonHTTPClient) compressBody(body io.Reader, opts RequestOptions) (io.Reader, bool, error) {
+	if c.compressOver <= 0 || body == nil || opts.DisableRequestCompression {
+		return body, false, nil
+	}
+	if headerValue(opts, "Content-Encoding") != "" {
+		return body, false, nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) < c.compressOver {
+		return bytes.NewReader(data), false, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, false, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, err
+	}
+	return bytes.NewReader(buf.Bytes()), true, nil
+}
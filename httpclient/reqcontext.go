@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID to the server so both sides can
+// correlate their logs.
+const RequestIDHeader = "X-Request-ID"
+
+// The client stores these values in the request context so middleware can
+// make decisions without re-parsing URLs. Tenant and endpoint names use
+// WithTenant and WithEndpoint.
+
+type requestIDKey struct{}
+
+type routeTemplateKey struct{}
+
+type attemptKey struct{}
+
+// WithRequestID returns a context carrying the request ID. Requests whose
+// context has none reuse their X-Request-ID header or get a random UUID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRouteTemplate returns a context carrying the unexpanded route of a
+// request, e.g. "/users/{id}". Use it to group requests in metrics and logs.
+func WithRouteTemplate(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeTemplateKey{}, route)
+}
+
+// RouteTemplateFromContext returns the route template stored in ctx, if any.
+func RouteTemplateFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeTemplateKey{}).(string)
+	return route
+}
+
+// withAttempt returns a context carrying the 1-based attempt number.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the 1-based attempt number stored in ctx, or 0.
+// Each attempt is sent with its own context, so middleware reads it from
+// resp.Request.Context() after calling the next Doer; transports see it on
+// every request.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// withRequestID makes sure req carries a request ID in both its context and
+// its RequestIDHeader, preferring an ID the caller already supplied.
+func withRequestID(req *http.Request) *http.Request {
+	header := req.Header.Get(RequestIDHeader)
+	id := RequestIDFromContext(req.Context())
+	if id == "" {
+		if id = header; id == "" {
+			id = newRequestID()
+		}
+		req = req.WithContext(WithRequestID(req.Context(), id))
+	}
+	if header == "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	return req
+}
+
+// newRequestID returns a random version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy decides whether a finished attempt is retried. resp is nil when
+// err is set, and attempt is the 1-based number of the attempt that just
+// finished. A delay > 0 replaces the backoff before the next attempt;
+// otherwise the RetryProfile backoff and Retry-After apply. Retry counts
+// still come from the client and endpoint settings.
+type RetryPolicy interface {
+	ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration)
+}
+
+// RetryPolicyFunc adapts a function to RetryPolicy.
+type RetryPolicyFunc func(resp *http.Response, err error, attempt int) (bool, time.Duration)
+
+func (f RetryPolicyFunc) ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	return f(resp, err, attempt)
+}
+
+// DefaultRetryStatuses are retried by RetryRules without Statuses: statuses
+// reporting overload or an unreachable upstream, which later attempts may
+// not see.
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy retries network errors and DefaultRetryStatuses.
+var DefaultRetryPolicy RetryPolicy = RetryRules{}
+
+// RetryRules is a RetryPolicy based on response statuses.
+type RetryRules struct {
+	// Statuses are retried (default DefaultRetryStatuses).
+	Statuses []int
+	// ServerErrors retries every 5xx status except those in Exclude, such as
+	// 501 Not Implemented or 505 HTTP Version Not Supported, which will not
+	// change on another attempt.
+	ServerErrors bool
+	Exclude      []int
+	// SkipNetworkErrors fails right away on transport errors instead of
+	// retrying them.
+	SkipNetworkErrors bool
+	// Delays sets a fixed wait before retrying specific statuses, e.g. 503.
+	Delays map[int]time.Duration
+}
+
+// ShouldRetry implements RetryPolicy.
+func (r RetryRules) ShouldRetry(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if err != nil {
+		return !r.SkipNetworkErrors, 0
+	}
+	code := resp.StatusCode
+	if slices.Contains(r.Exclude, code) {
+		return false, 0
+	}
+	statuses := r.Statuses
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+	if !slices.Contains(statuses, code) && !(r.ServerErrors && code >= 500) {
+		return false, 0
+	}
+	return true, r.Delays[code]
+}
+
+type retryPolicyKey struct{}
+
+// withRetryPolicy returns a context carrying a retry policy that overrides the
+// client's.
+func withRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// retryPolicyFor returns the retry policy for req.
+func (c *CommonHTTPClient) retryPolicyFor(req *http.Request) RetryPolicy {
+	if p, ok := req.Context().Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return c.retryPolicy
+}
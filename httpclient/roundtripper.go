@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"net/http"
+)
+
+// Transport returns an http.RoundTripper that sends every request through the
+// client's default headers, middleware, retries and logging. It lets third-party SDKs that
+// accept an *http.Client transparently gain this package's behavior.
+func (c *CommonHTTPClient) Transport() http.RoundTripper {
+	return &roundTripper{client: c}
+}
+
+// StdClient returns an *http.Client backed by Transport. Timeouts and redirects
+// are handled by the wrapped client, so the returned client sets neither.
+func (c *CommonHTTPClient) StdClient() *http.Client {
+	return &http.Client{Transport: c.Transport()}
+}
+
+// roundTripper adapts CommonHTTPClient to the http.RoundTripper interface.
+type roundTripper struct {
+	client *CommonHTTPClient
+}
+
+// RoundTrip implements http.RoundTripper. The incoming request is cloned so the
+// caller's request is never modified, as the interface contract requires.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+
+	// Default headers only fill gaps; headers set by the caller win
+	for k, v := range rt.client.defaultHeaders {
+		if out.Header.Get(k) == "" {
+			out.Header.Set(k, v)
+		}
+	}
+
+	return rt.client.dispatch(out)
+}
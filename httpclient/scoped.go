@@ -0,0 +1,28 @@
+package httpclient
+
+import (
+	"path"
+	"strings"
+)
+
+// Scoped returns a derived client whose relative request paths are rooted
+// under prefix, e.g. client.Scoped("/v2/admin") sends Path "/users" to
+// "<base>/v2/admin/users". The derived client shares the transport, limits,
+// stats and every other setting with c. Scopes nest.
+func (c *CommonHTTPClient) Scoped(prefix string) *CommonHTTPClient {
+	scoped := *c
+	scoped.pathPrefix = path.Join("/", c.pathPrefix, prefix)
+	return &scoped
+}
+
+// scopedPath applies the client's path prefix to a request path. Absolute
+// URLs are left untouched.
+func (c *CommonHTTPClient) scopedPath(p string) string {
+	if c.pathPrefix == "" || strings.Contains(p, "://") {
+		return p
+	}
+	if p == "" {
+		return c.pathPrefix
+	}
+	return strings.TrimSuffix(c.pathPrefix, "/") + "/" + strings.TrimPrefix(p, "/")
+}
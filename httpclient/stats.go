@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"httpclient/metrics"
+)
+
+// ByteCounts aggregates traffic for a client, host or route.
+type ByteCounts struct {
+	Requests      int64
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// Stats is a snapshot of the traffic sent through a client. Byte counts cover
+// request and response bodies, including bodies resent on retries. ByRoute is
+// keyed by endpoint name, or by method and route template; requests with
+// neither are counted under OtherRoute so the map stays bounded.
+type Stats struct {
+	Total   ByteCounts
+	ByHost  map[string]ByteCounts
+	ByRoute map[string]ByteCounts
+	// Cost is the spending tracked by ClientConfig.CostEstimator and Budget.
+	Cost CostStats
+}
+
+// OtherRoute is the Stats.ByRoute key for requests without an endpoint name or
+// route template.
+const OtherRoute = "other"
+
+// Stats returns a snapshot of the bytes sent and received by the client.
+func (c *CommonHTTPClient) Stats() Stats {
+	stats := c.stats.snapshot()
+	stats.Cost = c.costs.snapshot()
+	return stats
+}
+
+// clientStats accumulates ByteCounts under a mutex.
+type clientStats struct {
+	mu      sync.Mutex
+	total   ByteCounts
+	byHost  map[string]ByteCounts
+	byRoute map[string]ByteCounts
+}
+
+func newClientStats() *clientStats {
+	return &clientStats{
+		byHost:  make(map[string]ByteCounts),
+		byRoute: make(map[string]ByteCounts),
+	}
+}
+
+// recordBytes adds one finished request to the client's stats and, when the
+// recorder supports it, to the byte metrics.
+func (c *CommonHTTPClient) recordBytes(req *http.Request, sent, received int64) {
+	c.stats.record(req, sent, received)
+	if br, ok := c.metrics.(metrics.BytesRecorder); ok {
+		br.ObserveBytes(req.Method, req.URL.Host, sent, received)
+	}
+}
+
+// record adds one request to the totals. Routes are keyed by the endpoint name
+// when set, otherwise by method and route template. Raw paths carry IDs and
+// would grow the map without bound, so everything else lands in OtherRoute.
+func (s *clientStats) record(req *http.Request, sent, received int64) {
+	route := EndpointFromContext(req.Context())
+	if route == "" {
+		route = OtherRoute
+		if tmpl := RouteTemplateFromContext(req.Context()); tmpl != "" {
+			route = req.Method + " " + tmpl
+		}
+	}
+	add := func(b ByteCounts) ByteCounts {
+		b.Requests++
+		b.BytesSent += sent
+		b.BytesReceived += received
+		return b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total = add(s.total)
+	s.byHost[req.URL.Host] = add(s.byHost[req.URL.Host])
+	s.byRoute[route] = add(s.byRoute[route])
+}
+
+func (s *clientStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := Stats{
+		Total:   s.total,
+		ByHost:  make(map[string]ByteCounts, len(s.byHost)),
+		ByRoute: make(map[string]ByteCounts, len(s.byRoute)),
+	}
+	for k, v := range s.byHost {
+		out.ByHost[k] = v
+	}
+	for k, v := range s.byRoute {
+		out.ByRoute[k] = v
+	}
+	return out
+}
+
+// countingReadCloser adds the bytes read through it to a shared counter, so
+// bodies replayed on retries accumulate into one total.
+type countingReadCloser struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
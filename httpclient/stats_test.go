@@ -0,0 +1,100 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+// bytesRecorder captures ObserveBytes calls.
+type bytesRecorder struct {
+	mu             sync.Mutex
+	sent, received int64
+}
+
+func (r *bytesRecorder) ObserveRequest(string, string, int, time.Duration) {}
+func (r *bytesRecorder) ObserveRetry(string, string)                       {}
+
+func (r *bytesRecorder) ObserveBytes(_, _ string, sent, received int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent += sent
+	r.received += received
+}
+
+func TestStatsByRoute(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []RequestOptions
+		wantRoute string
+	}{
+		{
+			name: "endpoint name",
+			opts: []RequestOptions{
+				{Method: http.MethodPost, Path: "/users/1", Endpoint: "users.update"},
+				{Method: http.MethodPost, Path: "/users/2", Endpoint: "users.update"},
+			},
+			wantRoute: "users.update",
+		},
+		{
+			name: "route template",
+			opts: []RequestOptions{
+				{Method: http.MethodPost, Path: "/users/{id}", PathParams: map[string]string{"id": "1"}},
+				{Method: http.MethodPost, Path: "/users/{id}", PathParams: map[string]string{"id": "2"}},
+			},
+			wantRoute: "POST /users/{id}",
+		},
+		{
+			name: "raw paths",
+			opts: []RequestOptions{
+				{Method: http.MethodPost, Path: "/users/1"},
+				{Method: http.MethodPost, Path: "/users/2"},
+			},
+			wantRoute: OtherRoute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodPost, "/users/1").Reply(http.StatusOK, "done")
+			mock.On(http.MethodPost, "/users/2").Reply(http.StatusOK, "done")
+			rec := &bytesRecorder{}
+			base, _ := url.Parse("http://api.test")
+			c := NewCommonHTTPClient(ClientConfig{
+				BaseURL:    base,
+				Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+				HTTPClient: &http.Client{Transport: mock},
+				Metrics:    rec,
+			})
+
+			for _, opts := range tt.opts {
+				opts.Body = strings.NewReader("{}")
+				resp, err := c.Do(context.Background(), opts)
+				if err != nil {
+					t.Fatalf("Do: %v", err)
+				}
+				resp.Body.Close()
+			}
+
+			stats := c.Stats()
+			if len(stats.ByRoute) != 1 {
+				t.Errorf("routes = %v, want only %q", stats.ByRoute, tt.wantRoute)
+			}
+			want := ByteCounts{Requests: 2, BytesSent: 4, BytesReceived: 8}
+			if got := stats.ByRoute[tt.wantRoute]; got != want {
+				t.Errorf("ByRoute[%q] = %+v, want %+v", tt.wantRoute, got, want)
+			}
+			if rec.sent != want.BytesSent || rec.received != want.BytesReceived {
+				t.Errorf("recorded %d bytes sent, %d received, want %d and %d", rec.sent, rec.received, want.BytesSent, want.BytesReceived)
+			}
+		})
+	}
+}
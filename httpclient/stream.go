@@ -0,0 +1,31 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+)
+
+type streamKey struct{}
+
+// withStreaming marks a request context so the response body is not buffered.
+func withStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamKey{}, true)
+}
+
+func isStreaming(ctx context.Context) bool {
+	streaming, _ := ctx.Value(streamKey{}).(bool)
+	return streaming
+}
+
+// cancelOnClose releases a per-request timeout context when a streamed body
+// is closed, rather than when Do returns.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
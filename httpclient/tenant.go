@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Tenant holds per-tenant routing: where requests go and how they authenticate.
+type Tenant struct {
+	ID string
+	// BaseURL overrides the client base URL when set.
+	BaseURL *url.URL
+	// Headers are applied after the client defaults and before request headers,
+	// e.g. {"Authorization": "Bearer <tenant token>"}.
+	Headers map[string]string
+}
+
+// TenantResolver selects the tenant for a request from its context. Returning
+// a nil tenant and nil error routes the request with the client defaults.
+type TenantResolver func(ctx context.Context) (*Tenant, error)
+
+// ErrUnknownTenant is returned by TenantRegistry for unregistered tenant IDs.
+var ErrUnknownTenant = errors.New("httpclient: unknown tenant")
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant ID used by TenantRegistry.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the tenant ID stored in ctx, if any.
+func TenantFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+// TenantRegistry is a concurrency-safe set of tenants keyed by ID.
+type TenantRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+// NewTenantRegistry creates a registry with the given tenants.
+func NewTenantRegistry(tenants ...*Tenant) *TenantRegistry {
+	r := &TenantRegistry{tenants: make(map[string]*Tenant, len(tenants))}
+	for _, t := range tenants {
+		r.tenants[t.ID] = t
+	}
+	return r
+}
+
+// Register adds or replaces a tenant.
+func (r *TenantRegistry) Register(t *Tenant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants[t.ID] = t
+}
+
+// Remove deletes a tenant.
+func (r *TenantRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tenants, id)
+}
+
+// Resolve is a TenantResolver looking up the tenant ID set by WithTenant.
+// Requests without a tenant ID use the client defaults.
+func (r *TenantRegistry) Resolve(ctx context.Context) (*Tenant, error) {
+	id := TenantFromContext(ctx)
+	if id == "" {
+		return nil, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, id)
+	}
+	return t, nil
+}
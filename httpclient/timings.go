@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks one attempt down by phase, to tell slow DNS or handshakes
+// from a slow server. Phases that did not happen, e.g. DNS and Connect on a
+// reused connection, are zero.
+type Timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is the wait for the first response byte after the request was
+	// written, i.e. mostly server time.
+	TTFB time.Duration
+	// Transfer is the time from the first response byte until the body was
+	// read. It stays zero for streamed responses, which the caller reads.
+	Transfer   time.Duration
+	ConnReused bool
+}
+
+// LogValue renders the phases in milliseconds for structured logs.
+func (t Timings) LogValue() slog.Value {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	return slog.GroupValue(
+		slog.Float64("dns_ms", ms(t.DNS)),
+		slog.Float64("connect_ms", ms(t.Connect)),
+		slog.Float64("tls_ms", ms(t.TLS)),
+		slog.Float64("ttfb_ms", ms(t.TTFB)),
+		slog.Float64("transfer_ms", ms(t.Transfer)),
+		slog.Bool("conn_reused", t.ConnReused),
+	)
+}
+
+// timingTrace records the phases of one attempt through httptrace. Hedged
+// duplicates of the attempt share it; the phases seen last win.
+type timingTrace struct {
+	mu sync.Mutex
+	t  Timings
+
+	dnsStart, connectStart, tlsStart time.Time
+	wrote, firstByte                 time.Time
+}
+
+// withTimings returns req with a trace recording its phases, composed with
+// any trace already in its context.
+func withTimings(req *http.Request) (*http.Request, *timingTrace) {
+	tt := &timingTrace{}
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tt.mu.Lock()
+			tt.dnsStart = time.Now()
+			tt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tt.mu.Lock()
+			tt.t.DNS = time.Since(tt.dnsStart)
+			tt.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			tt.mu.Lock()
+			// Keep the first start when several addresses are raced
+			if tt.connectStart.IsZero() {
+				tt.connectStart = time.Now()
+			}
+			tt.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				return
+			}
+			tt.mu.Lock()
+			tt.t.Connect = time.Since(tt.connectStart)
+			tt.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			tt.mu.Lock()
+			tt.tlsStart = time.Now()
+			tt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tt.mu.Lock()
+			tt.t.TLS = time.Since(tt.tlsStart)
+			tt.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tt.mu.Lock()
+			tt.t.ConnReused = info.Reused
+			tt.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tt.mu.Lock()
+			tt.wrote = time.Now()
+			tt.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tt.mu.Lock()
+			tt.firstByte = time.Now()
+			if !tt.wrote.IsZero() {
+				tt.t.TTFB = tt.firstByte.Sub(tt.wrote)
+			}
+			tt.mu.Unlock()
+		},
+	})
+	return req.WithContext(ctx), tt
+}
+
+// timings returns the phases recorded so far.
+func (tt *timingTrace) timings() Timings {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.t
+}
+
+// finish records the body as fully read and returns the final phases.
+func (tt *timingTrace) finish() Timings {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if !tt.firstByte.IsZero() {
+		tt.t.Transfer = time.Since(tt.firstByte)
+	}
+	return tt.t
+}
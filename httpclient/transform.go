@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// BodyTransformer rewrites payloads on the wire, e.g. for field-level
+// encryption, custom compression or envelope wrapping. Both methods may adjust
+// the headers describing the body, such as Content-Type or Content-Encoding.
+type BodyTransformer interface {
+	// EncodeRequest transforms an outgoing request body.
+	EncodeRequest(body []byte, header http.Header) ([]byte, error)
+	// DecodeResponse reverses the transformation on a response body.
+	DecodeResponse(body []byte, header http.Header) ([]byte, error)
+}
+
+// BodyTransform registers a BodyTransformer for a route. Routes are selected
+// like RateLimit profiles: by endpoint Name first, then by path.Match Pattern
+// against the URL path.
+type BodyTransform struct {
+	Name        string
+	Pattern     string
+	Transformer BodyTransformer
+}
+
+// BodyTransforms returns middleware applying the first matching transform to
+// request bodies and to buffered response bodies. Streamed responses are
+// passed through untouched.
+func BodyTransforms(routes ...BodyTransform) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			t := matchTransform(routes, req)
+			if t == nil {
+				return next.Do(req)
+			}
+			if err := encodeRequestBody(req, t); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.Do(req)
+			if err != nil || isStreaming(req.Context()) {
+				return resp, err
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if len(body) > 0 {
+				if body, err = t.DecodeResponse(body, resp.Header); err != nil {
+					return nil, fmt.Errorf("decode response body: %w", err)
+				}
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			resp.ContentLength = int64(len(body))
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			return resp, nil
+		})
+	}
+}
+
+// matchTransform returns the transformer registered for req, if any.
+func matchTransform(routes []BodyTransform, req *http.Request) BodyTransformer {
+	endpoint := EndpointFromContext(req.Context())
+	for _, r := range routes {
+		if endpoint != "" && r.Name == endpoint {
+			return r.Transformer
+		}
+	}
+	for _, r := range routes {
+		if r.Pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(r.Pattern, req.URL.Path); ok {
+			return r.Transformer
+		}
+	}
+	return nil
+}
+
+// encodeRequestBody replaces the body of req with its encoded form, keeping it
+// replayable for retries.
+func encodeRequestBody(req *http.Request, t BodyTransformer) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	if body, err = t.EncodeRequest(body, req.Header); err != nil {
+		return fmt.Errorf("encode request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return nil
+}
+
+// GzipTransformer compresses request bodies and decompresses gzip-encoded
+// responses that the transport did not already decode.
+type GzipTransformer struct{}
+
+// EncodeRequest gzips body and sets Content-Encoding.
+func (GzipTransformer) EncodeRequest(body []byte, header http.Header) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	header.Set("Content-Encoding", "gzip")
+	return buf.Bytes(), nil
+}
+
+// DecodeResponse gunzips body when the response is gzip-encoded.
+func (GzipTransformer) DecodeResponse(body []byte, header http.Header) ([]byte, error) {
+	if header.Get("Content-Encoding") != "gzip" {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	if body, err = io.ReadAll(zr); err != nil {
+		return nil, err
+	}
+	header.Del("Content-Encoding")
+	return body, nil
+}
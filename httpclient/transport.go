@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrSSRFProtectionUnavailable is returned by NewClient when SSRFProtection
+// is set but HTTPClient uses a custom RoundTripper, which it cannot guard.
+var ErrSSRFProtectionUnavailable = errors.New("httpclient: SSRF protection needs an *http.Transport")
+
+// withTransport returns a copy of hc whose transport is a clone of its
+// *http.Transport (or of http.DefaultTransport) passed through configure, so
+// neither the caller's client nor a shared transport is ever mutated.
+// Custom RoundTrippers cannot be configured: with ssrf set, the SSRF
+// protection would be missing and an error is returned; otherwise a warning
+// is logged and hc is returned unchanged.
+func withTransport(hc *http.Client, logger *slog.Logger, configure func(*http.Transport), ssrf bool) (*http.Client, error) {
+	var base *http.Transport
+	switch t := hc.Transport.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case *http.Transport:
+		base = t
+	default:
+		if ssrf {
+			return nil, fmt.Errorf("%w, got %T", ErrSSRFProtectionUnavailable, t)
+		}
+		logger.Warn("Cannot apply transport settings to custom RoundTripper",
+			slog.String("transport", fmt.Sprintf("%T", t)))
+		return hc, nil
+	}
+
+	transport := base.Clone()
+	configure(transport)
+
+	clone := *hc
+	clone.Transport = transport
+	return &clone, nil
+}
+
+// needsTransport reports whether any setting requires a configured transport.
+func (cfg *ClientConfig) needsTransport() bool {
+	return cfg.SSRFProtection != nil || cfg.ConnectTimeout > 0 ||
+		cfg.TLSHandshakeTimeout > 0 || cfg.ResponseHeaderTimeout > 0 ||
+		cfg.MaxIdleConns > 0 || cfg.MaxIdleConnsPerHost > 0 || cfg.MaxConnsPerHost > 0 ||
+		cfg.IdleConnTimeout > 0 || cfg.DisableKeepAlives || cfg.ProxyURL != nil ||
+		cfg.DisableDecompression || cfg.DNSCache != nil
+}
+
+// configureTransport applies transport-level settings from the config.
+func (cfg *ClientConfig) configureTransport(t *http.Transport) {
+	// Set before SSRF protection, which disables proxies
+	if cfg.ProxyURL != nil {
+		t.Proxy = http.ProxyURL(cfg.ProxyURL)
+	}
+	if cfg.ConnectTimeout > 0 || cfg.SSRFProtection != nil || cfg.DNSCache != nil {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		if cfg.ConnectTimeout > 0 {
+			dialer.Timeout = cfg.ConnectTimeout
+		}
+		if cfg.SSRFProtection != nil {
+			cfg.SSRFProtection.apply(t, dialer)
+		}
+		t.DialContext = dialer.DialContext
+		if cfg.DNSCache != nil {
+			// Addresses from the cache still pass the SSRF check when dialed
+			t.DialContext = cfg.DNSCache.dialContext(dialer.DialContext)
+		}
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+	if cfg.ResponseHeaderTimeout > 0 {
+		t.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	}
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.DisableKeepAlives {
+		t.DisableKeepAlives = true
+	}
+	if cfg.DisableDecompression {
+		t.DisableCompression = true
+	}
+}
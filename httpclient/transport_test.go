@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestNewClientSSRFProtectionFailsClosed(t *testing.T) {
+	mock := httpmock.New()
+	tests := []struct {
+		name    string
+		cfg     ClientConfig
+		wantErr error
+	}{
+		{name: "default transport", cfg: ClientConfig{SSRFProtection: &SSRFPolicy{}}},
+		{
+			name: "custom transport",
+			cfg:  ClientConfig{SSRFProtection: &SSRFPolicy{}, HTTPClient: &http.Client{Transport: &http.Transport{}}},
+		},
+		{
+			name:    "custom RoundTripper",
+			cfg:     ClientConfig{SSRFProtection: &SSRFPolicy{}, HTTPClient: &http.Client{Transport: mock}},
+			wantErr: ErrSSRFProtectionUnavailable,
+		},
+		{
+			// Other transport settings are only skipped with a warning
+			name: "custom RoundTripper without SSRF protection",
+			cfg:  ClientConfig{ConnectTimeout: time.Second, HTTPClient: &http.Client{Transport: mock}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			c, err := NewClient(tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewClient error = %v, want %v", err, tt.wantErr)
+			}
+			if (c == nil) != (tt.wantErr != nil) {
+				t.Errorf("client = %v, want one only without an error", c)
+			}
+		})
+	}
+}
+
+func TestNewCommonHTTPClientPanicsWithoutSSRFProtection(t *testing.T) {
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrSSRFProtectionUnavailable) {
+			t.Errorf("recovered %v, want ErrSSRFProtectionUnavailable", err)
+		}
+	}()
+	NewCommonHTTPClient(ClientConfig{
+		SSRFProtection: &SSRFPolicy{},
+		HTTPClient:     &http.Client{Transport: httpmock.New()},
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
@@ -0,0 +1,144 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Send executes opts with c (the default client when nil), marshaling body
+// when non-nil with the codec for the Content-Type header (JSON when unset)
+// and decoding the response into T with the codec matching the response
+// Content-Type, or the request codec when none matches. Accept defaults to
+// the request codec's media type. Responses with status >= 400 return an
+// *APIError; 204 and empty bodies yield the zero T. The returned response
+// has its body consumed and closed.
+func Send[T any](ctx context.Context, c *CommonHTTPClient, opts RequestOptions, body any) (T, *http.Response, error) {
+	var out T
+	if c == nil {
+		c = DefaultClient()
+	}
+
+	var codec Codec = JSONCodec{}
+	contentType := headerValue(opts, "Content-Type")
+	if contentType != "" {
+		found, ok := CodecFor(contentType)
+		if !ok {
+			return out, nil, fmt.Errorf("no codec for content type %q", contentType)
+		}
+		codec = found
+	}
+
+	headers := make(map[string]string, len(opts.Headers)+2)
+	if headerValue(opts, "Accept") == "" {
+		headers["Accept"] = codec.ContentType()
+	}
+	if body != nil {
+		payload, err := codec.Marshal(body)
+		if err != nil {
+			return out, nil, fmt.Errorf("marshal request body: %w", err)
+		}
+		opts.Body = bytes.NewReader(payload)
+		opts.GetBody = nil
+		if contentType == "" {
+			headers["Content-Type"] = codec.ContentType()
+		}
+	}
+	for k, v := range opts.Headers {
+		headers[k] = v
+	}
+	opts.Headers = headers
+
+	resp, err := c.Do(ctx, opts)
+	if err != nil {
+		return out, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return out, resp, c.newAPIError(resp)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return out, resp, fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode == http.StatusNoContent || len(bytes.TrimSpace(data)) == 0 {
+		return out, resp, nil
+	}
+	if found, ok := CodecFor(resp.Header.Get("Content-Type")); ok {
+		codec = found
+	}
+	if err := codec.Unmarshal(data, &out); err != nil {
+		return out, resp, fmt.Errorf("decode %T response: %w", out, err)
+	}
+	return out, resp, nil
+}
+
+// SendJSON is Send with JSON request bodies; the response is still decoded
+// according to its Content-Type. JSON is Send's default codec, so like Send
+// it only sets Content-Type when there is a body.
+func SendJSON[T any](ctx context.Context, c *CommonHTTPClient, opts RequestOptions, body any) (T, *http.Response, error) {
+	return Send[T](ctx, c, opts, body)
+}
+
+// Decode reads and closes the body of resp and unmarshals it into v with the
+// codec matching its Content-Type, or JSON when none matches.
+func Decode(resp *http.Response, v any) error {
+	if resp.Body == nil {
+		return errors.New("no response body")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	codec, ok := CodecFor(resp.Header.Get("Content-Type"))
+	if !ok {
+		codec = JSONCodec{}
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// headerValue looks up a header in opts case-insensitively; Header takes
+// precedence over Headers, as when the request is built.
+func headerValue(opts RequestOptions, name string) string {
+	for k, values := range opts.Header {
+		if strings.EqualFold(k, name) {
+			if len(values) == 0 {
+				return ""
+			}
+			return values[0]
+		}
+	}
+	for k, v := range opts.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// GetJSON issues a GET to path with the given query parameters and decodes the
+// JSON response into T.
+func GetJSON[T any](ctx context.Context, c *CommonHTTPClient, path string, query map[string]string) (T, error) {
+	out, _, err := SendJSON[T](ctx, c, RequestOptions{
+		Method:      http.MethodGet,
+		Path:        path,
+		QueryParams: query,
+	}, nil)
+	return out, err
+}
+
+// PostJSON marshals body as JSON, POSTs it to path and decodes the JSON
+// response into T.
+func PostJSON[T any](ctx context.Context, c *CommonHTTPClient, path string, body any) (T, error) {
+	out, _, err := SendJSON[T](ctx, c, RequestOptions{
+		Method: http.MethodPost,
+		Path:   path,
+	}, body)
+	return out, err
+}
@@ -0,0 +1,251 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpclient/metrics"
+)
+
+// ErrInvalidSignature is returned by VerifyWebhookSignature for signatures
+// that are missing, malformed, stale or do not match.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookConfig configures a WebhookSender.
+type WebhookConfig struct {
+	// Secret keys the HMAC-SHA256 signature of every delivery.
+	Secret []byte
+	// SignatureHeader carries the signature (default "Webhook-Signature").
+	SignatureHeader string
+	// FormatSignature renders the header value from the Unix timestamp and
+	// the MAC over "<timestamp>.<body>"; the default is "t=<ts>,v1=<hex>".
+	FormatSignature func(timestamp int64, mac []byte) string
+	// MaxAttempts bounds the attempts per delivery (default 5). Backoff is
+	// the initial wait between attempts, doubling up to MaxBackoff (defaults
+	// 1s and 1m); Retry-After is honored when longer.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// OnDeadLetter is called for deliveries that exhausted their attempts or
+	// were rejected with a non-retriable status, e.g. to park them for replay.
+	OnDeadLetter func(d *WebhookDelivery, err error)
+}
+
+// WebhookDelivery describes one webhook and the outcome of sending it. ID is
+// sent as Idempotency-Key on every attempt so receivers can deduplicate.
+type WebhookDelivery struct {
+	ID         string
+	URL        string
+	Event      string
+	Payload    []byte
+	Attempts   int
+	StatusCode int
+}
+
+// WebhookSender delivers signed webhooks with retries through a
+// CommonHTTPClient. Its own retries come on top of the client's, so a client
+// with MaxRetries 0 is usually the right choice.
+type WebhookSender struct {
+	client *CommonHTTPClient
+	cfg    WebhookConfig
+}
+
+// NewWebhookSender creates a sender using c (the default client when nil).
+func NewWebhookSender(c *CommonHTTPClient, cfg WebhookConfig) *WebhookSender {
+	if c == nil {
+		c = DefaultClient()
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "Webhook-Signature"
+	}
+	if cfg.FormatSignature == nil {
+		cfg.FormatSignature = func(ts int64, mac []byte) string {
+			return "t=" + strconv.FormatInt(ts, 10) + ",v1=" + hex.EncodeToString(mac)
+		}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	return &WebhookSender{client: c, cfg: cfg}
+}
+
+// Send marshals payload as JSON (byte slices are sent as-is) and POSTs it to
+// target until it is acknowledged with a 2xx status. It returns the delivery and
+// a non-nil error when it ended in the dead letter callback.
+func (s *WebhookSender) Send(ctx context.Context, target, event string, payload any) (*WebhookDelivery, error) {
+	body, ok := payload.([]byte)
+	if !ok {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("marshal webhook payload: %w", err)
+		}
+	}
+	d := &WebhookDelivery{ID: newRequestID(), URL: target, Event: event, Payload: body}
+	return d, s.Deliver(ctx, d)
+}
+
+// Deliver sends d, e.g. one replayed from a dead letter queue, keeping its ID.
+func (s *WebhookSender) Deliver(ctx context.Context, d *WebhookDelivery) error {
+	backoff := s.cfg.Backoff
+	var lastErr error
+	for d.Attempts < s.cfg.MaxAttempts {
+		d.Attempts++
+		resp, err := s.attempt(ctx, d)
+		d.StatusCode = 0
+		var apiErr *APIError
+		switch {
+		case resp != nil:
+			d.StatusCode = resp.StatusCode
+			resp.Body.Close()
+		case errors.As(err, &apiErr):
+			// ErrorOnNon2xx turned the status into an error
+			d.StatusCode = apiErr.StatusCode
+		}
+		s.observeAttempt(ctx, d, err)
+
+		retriable := d.StatusCode >= 500 || d.StatusCode == http.StatusRequestTimeout ||
+			d.StatusCode == http.StatusTooManyRequests || (err != nil && d.StatusCode == 0)
+		switch {
+		case err == nil && d.StatusCode < 300:
+			s.observeDelivery(d, "delivered")
+			return nil
+		case err == nil:
+			lastErr = fmt.Errorf("webhook %s: status %d", d.ID, d.StatusCode)
+		default:
+			lastErr = err
+		}
+		if !retriable || ctx.Err() != nil || d.Attempts >= s.cfg.MaxAttempts {
+			break
+		}
+
+		delay := max(backoff, s.client.retryDelay(resp, backoff))
+		if err := sleepContext(ctx, delay); err != nil {
+			lastErr = err
+			break
+		}
+		backoff = min(backoff*2, s.cfg.MaxBackoff)
+	}
+
+	s.observeDelivery(d, "dead_letter")
+	if s.cfg.OnDeadLetter != nil {
+		s.cfg.OnDeadLetter(d, lastErr)
+	}
+	return lastErr
+}
+
+// attempt signs and sends one attempt of d.
+func (s *WebhookSender) attempt(ctx context.Context, d *WebhookDelivery) (*http.Response, error) {
+	ts := time.Now().Unix()
+	return s.client.Do(ctx, RequestOptions{
+		Method: http.MethodPost,
+		Path:   d.URL,
+		Headers: map[string]string{
+			"Content-Type":        "application/json",
+			"Idempotency-Key":     d.ID,
+			"Webhook-Event":       d.Event,
+			s.cfg.SignatureHeader: s.cfg.FormatSignature(ts, webhookMAC(s.cfg.Secret, ts, d.Payload)),
+		},
+		Body: bytes.NewReader(d.Payload),
+	})
+}
+
+func (s *WebhookSender) observeAttempt(ctx context.Context, d *WebhookDelivery, err error) {
+	level := slog.LevelInfo
+	if err != nil || d.StatusCode >= 300 {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("delivery_id", d.ID),
+		slog.String("event", d.Event),
+		slog.String("url", s.logURL(d)),
+		slog.Int("attempt", d.Attempts),
+		slog.Int("status_code", d.StatusCode),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	s.client.logger.LogAttrs(ctx, level, "Webhook delivery attempt", attrs...)
+	if wr, ok := s.client.metrics.(metrics.WebhookRecorder); ok {
+		wr.ObserveWebhookAttempt(d.Event, d.StatusCode)
+	}
+}
+
+func (s *WebhookSender) observeDelivery(d *WebhookDelivery, outcome string) {
+	if outcome != "delivered" {
+		s.client.logger.Error("Webhook dead-lettered",
+			slog.String("delivery_id", d.ID),
+			slog.String("event", d.Event),
+			slog.String("url", s.logURL(d)),
+			slog.Int("attempts", d.Attempts),
+		)
+	}
+	if wr, ok := s.client.metrics.(metrics.WebhookRecorder); ok {
+		wr.ObserveWebhookDelivery(d.Event, outcome)
+	}
+}
+
+// logURL redacts credentials in the query or userinfo of the delivery URL.
+func (s *WebhookSender) logURL(d *WebhookDelivery) string {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return d.URL
+	}
+	return s.client.redactor.URL(u)
+}
+
+func webhookMAC(secret []byte, ts int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// VerifyWebhookSignature checks a signature header in the default
+// "t=<ts>,v1=<hex>" format against body, rejecting timestamps more than
+// tolerance away from now. It is meant for receivers and for tests.
+func VerifyWebhookSignature(secret []byte, header string, body []byte, tolerance time.Duration) error {
+	var ts int64
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	want := webhookMAC(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,407 @@
+// Package websocket dials ws and wss endpoints using the same
+// httpclient.ClientConfig as the HTTP client: base URL, default headers,
+// RefreshAuth, logger, log flags, redaction, proxy and TLS settings.
+//
+// A Conn reconnects automatically with backoff when the connection drops and
+// keeps it alive with pings:
+//
+//	conn, err := websocket.Dial(ctx, websocket.Config{Client: cfg, Path: "/stream"})
+//	if err != nil {
+//		return err
+//	}
+//	defer conn.Close()
+//	for {
+//		msg, err := conn.Read(ctx)
+//		...
+//	}
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"httpclient/httpclient"
+	"httpclient/utils"
+
+	gws "github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned by operations on a closed Conn.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Message types, matching RFC 6455 opcodes.
+const (
+	TextMessage   = gws.TextMessage
+	BinaryMessage = gws.BinaryMessage
+)
+
+// Config configures a Conn.
+type Config struct {
+	// Client supplies the shared settings. Path is joined to Client.BaseURL
+	// and http(s) schemes become ws(s).
+	Client  httpclient.ClientConfig
+	Path    string
+	Headers map[string]string
+	// PingInterval is how often pings are sent (default 30s). A connection
+	// with no pong or message within PongTimeout (default twice the
+	// interval) is considered dead and replaced.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// ReconnectBackoff is the initial wait between reconnect attempts,
+	// doubling up to MaxReconnectBackoff (defaults 1s and 30s).
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+	// DisableReconnect makes Read and Write fail once the connection drops.
+	DisableReconnect bool
+	// OnConnect, if set, runs after every successful dial, e.g. to resend
+	// subscriptions after a reconnect. send writes to the new connection
+	// directly; an error closes it.
+	OnConnect func(ctx context.Context, send func(Message) error) error
+}
+
+// Message is a data frame.
+type Message struct {
+	Type int
+	Data []byte
+}
+
+// Conn is a reconnecting WebSocket connection. Read must not be called
+// concurrently with itself; Write is safe for concurrent use. Messages sent
+// by the server while the connection is down are lost.
+type Conn struct {
+	cfg      Config
+	url      *url.URL
+	dialer   *gws.Dialer
+	logger   *slog.Logger
+	redactor *utils.Redactor
+
+	mu       sync.Mutex
+	ws       *gws.Conn
+	auth     string
+	closed   bool
+	writeMu  sync.Mutex
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// Dial connects to cfg.Path. Reconnects after a later drop happen inside Read
+// and Write.
+func Dial(ctx context.Context, cfg Config) (*Conn, error) {
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = 2 * cfg.PingInterval
+	}
+	if cfg.ReconnectBackoff <= 0 {
+		cfg.ReconnectBackoff = time.Second
+	}
+	if cfg.MaxReconnectBackoff <= 0 {
+		cfg.MaxReconnectBackoff = 30 * time.Second
+	}
+	logger := cfg.Client.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	u, err := utils.JoinURL(cfg.Client.BaseURL, cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+
+	c := &Conn{
+		cfg:      cfg,
+		url:      u,
+		dialer:   newDialer(cfg.Client),
+		logger:   logger,
+		redactor: utils.NewRedactor(cfg.Client.RedactHeaders, cfg.Client.RedactQueryParams, cfg.Client.RedactBodyJSONPaths),
+		done:     make(chan struct{}),
+	}
+	ws, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.ws = ws
+	go c.keepalive()
+	return c, nil
+}
+
+// newDialer derives proxy, TLS and handshake settings from the client config.
+func newDialer(cfg httpclient.ClientConfig) *gws.Dialer {
+	d := &gws.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+	if cfg.HTTPClient != nil {
+		if t, ok := cfg.HTTPClient.Transport.(*http.Transport); ok {
+			d.Proxy = t.Proxy
+			d.NetDialContext = t.DialContext
+			if t.TLSClientConfig != nil {
+				d.TLSClientConfig = t.TLSClientConfig.Clone()
+			}
+		}
+	}
+	if cfg.ProxyURL != nil {
+		d.Proxy = http.ProxyURL(cfg.ProxyURL)
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		d.HandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+	return d
+}
+
+// dial performs one handshake, refreshing credentials once after a 401.
+func (c *Conn) dial(ctx context.Context) (*gws.Conn, error) {
+	header := make(http.Header)
+	for k, v := range c.cfg.Client.DefaultHeaders {
+		header.Set(k, v)
+	}
+	for k, v := range c.cfg.Headers {
+		header.Set(k, v)
+	}
+	if c.auth != "" {
+		header.Set("Authorization", c.auth)
+	}
+
+	c.logHandshake(header)
+	ws, resp, err := c.dialer.DialContext(ctx, c.url.String(), header)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized && c.cfg.Client.RefreshAuth != nil {
+		auth, refreshErr := c.cfg.Client.RefreshAuth(ctx)
+		if refreshErr != nil {
+			return nil, fmt.Errorf("websocket: refresh auth: %w", refreshErr)
+		}
+		c.auth = auth
+		header.Set("Authorization", auth)
+		ws, resp, err = c.dialer.DialContext(ctx, c.url.String(), header)
+	}
+	if err != nil {
+		if resp != nil {
+			err = fmt.Errorf("websocket: dial %s: %w (status %d)", c.redactor.URL(c.url), err, resp.StatusCode)
+		}
+		c.logger.Error("WebSocket dial failed", slog.String("url", c.logURL()), slog.Any("error", err))
+		return nil, err
+	}
+
+	ws.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
+	})
+	c.logger.Info("WebSocket connected", slog.String("url", c.logURL()))
+
+	if c.cfg.OnConnect != nil {
+		send := func(m Message) error {
+			if err := ws.WriteMessage(m.Type, m.Data); err != nil {
+				return err
+			}
+			c.logFrame("WebSocket frame sent", m)
+			return nil
+		}
+		if err := c.cfg.OnConnect(ctx, send); err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("websocket: on connect: %w", err)
+		}
+	}
+	return ws, nil
+}
+
+// current returns the live connection.
+func (c *Conn) current() (*gws.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	return c.ws, nil
+}
+
+// reconnect replaces broken unless another caller already did, retrying with
+// backoff until it succeeds, ctx is done or the Conn is closed.
+func (c *Conn) reconnect(ctx context.Context, broken *gws.Conn, cause error) (*gws.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if c.ws != broken {
+		return c.ws, nil
+	}
+	broken.Close()
+	if c.cfg.DisableReconnect {
+		return nil, cause
+	}
+
+	c.logger.Warn("WebSocket connection lost", slog.String("url", c.logURL()), slog.Any("error", cause))
+	backoff := c.cfg.ReconnectBackoff
+	for {
+		ws, err := c.dial(ctx)
+		if err == nil {
+			c.ws = ws
+			return ws, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClosed
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, c.cfg.MaxReconnectBackoff)
+	}
+}
+
+// Read returns the next data message, reconnecting transparently when the
+// connection drops. Canceling ctx also drops the connection; the next Read
+// or Write re-establishes it.
+func (c *Conn) Read(ctx context.Context) (Message, error) {
+	ws, err := c.current()
+	if err != nil {
+		return Message{}, err
+	}
+	for {
+		msgType, data, err := c.readMessage(ctx, ws)
+		if err == nil {
+			msg := Message{Type: msgType, Data: data}
+			c.logFrame("WebSocket frame received", msg)
+			return msg, nil
+		}
+		if ctx.Err() != nil {
+			return Message{}, ctx.Err()
+		}
+		if ws, err = c.reconnect(ctx, ws, err); err != nil {
+			return Message{}, err
+		}
+	}
+}
+
+// readMessage reads from ws, unblocking the read when ctx is done.
+func (c *Conn) readMessage(ctx context.Context, ws *gws.Conn) (int, []byte, error) {
+	// Liveness only counts while reading: pongs are processed by reads, so
+	// time spent by the caller between Reads must not expire the deadline
+	ws.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
+	stop := context.AfterFunc(ctx, func() {
+		ws.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	return ws.ReadMessage()
+}
+
+// Write sends msg, reconnecting and retrying once if the connection dropped.
+func (c *Conn) Write(ctx context.Context, msg Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	ws, err := c.current()
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		ws.SetWriteDeadline(deadline)
+	}
+	if err = ws.WriteMessage(msg.Type, msg.Data); err != nil {
+		if ws, err = c.reconnect(ctx, ws, err); err != nil {
+			return err
+		}
+		if err = ws.WriteMessage(msg.Type, msg.Data); err != nil {
+			return err
+		}
+	}
+	c.logFrame("WebSocket frame sent", msg)
+	return nil
+}
+
+// WriteText sends a text message.
+func (c *Conn) WriteText(ctx context.Context, text string) error {
+	return c.Write(ctx, Message{Type: TextMessage, Data: []byte(text)})
+}
+
+// keepalive pings the live connection until the Conn is closed.
+func (c *Conn) keepalive() {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		ws, err := c.current()
+		if err != nil {
+			return
+		}
+		// A failed ping surfaces as a read error, which reconnects
+		ws.WriteControl(gws.PingMessage, nil, time.Now().Add(c.cfg.PingInterval))
+	}
+}
+
+// Close sends a close frame and closes the connection. Blocked Read calls
+// return ErrClosed.
+func (c *Conn) Close() error {
+	// Stop a pending reconnect first; it holds mu while backing off
+	c.doneOnce.Do(func() { close(c.done) })
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	ws := c.ws
+	c.mu.Unlock()
+
+	ws.WriteControl(gws.CloseMessage, gws.FormatCloseMessage(gws.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.logger.Info("WebSocket closed", slog.String("url", c.logURL()))
+	if err := ws.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// logURL renders the endpoint for logs, honoring DisableLogQuery.
+func (c *Conn) logURL() string {
+	if c.cfg.Client.DisableLogQuery {
+		u := *c.url
+		u.RawQuery = ""
+		return c.redactor.URL(&u)
+	}
+	return c.redactor.URL(c.url)
+}
+
+func (c *Conn) logHandshake(header http.Header) {
+	var headers map[string][]string
+	if !c.cfg.Client.DisableLogHeaders {
+		headers = c.redactor.Headers(header)
+	}
+	c.logger.Info("WebSocket dial", slog.String("url", c.logURL()), slog.Any("headers", headers))
+}
+
+func (c *Conn) logFrame(msg string, m Message) {
+	var body string
+	if !c.cfg.Client.DisableLogBody {
+		if m.Type == TextMessage {
+			body = c.redactor.Body(string(m.Data))
+		} else {
+			body = fmt.Sprintf("<binary, %d bytes>", len(m.Data))
+		}
+	}
+	c.logger.Info(msg,
+		slog.String("url", c.logURL()),
+		slog.Int("type", m.Type),
+		slog.Int("size", len(m.Data)),
+		slog.String("body", body),
+	)
+}
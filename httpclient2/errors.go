@@ -0,0 +1,78 @@
+package httpclient2
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"httpclient/utils"
+)
+
+// maxErrorBodySnippet is how much of an error response body APIError keeps
+const maxErrorBodySnippet = 4096
+
+// maxProblemBody bounds the problem details document decoded into APIError
+const maxProblemBody = 64 << 10
+
+// errorURLRedactor masks credentials sent in the query, such as the
+// WithAPIKey "api_key" parameter, in APIError.URL, which error messages and
+// logs repeat
+var errorURLRedactor = utils.NewRedactor(nil, []string{"api_key", "access_token"}, nil)
+
+// APIError is returned by Do for responses with status >= 400 when the client
+// is created with WithErrorOnNon2xx. Use errors.As to inspect it
+type APIError struct {
+	StatusCode int
+	Method     string
+	// URL is the request URL with credential query parameters masked
+	URL    string
+	Header http.Header
+	// Body holds up to the first 4 KiB of the response body
+	Body []byte
+	// Problem holds the decoded body of application/problem+json responses
+	// (RFC 9457), also reachable with errors.As
+	Problem *utils.ProblemDetails
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: status %d", e.Method, e.URL, e.StatusCode)
+	if e.Problem != nil {
+		return msg + ": " + e.Problem.Error()
+	}
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+	return msg
+}
+
+// Unwrap returns the problem details, if any
+func (e *APIError) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+	return e.Problem
+}
+
+// newAPIError builds an APIError from resp and closes its body
+func newAPIError(resp *http.Response) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode, Header: resp.Header}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = errorURLRedactor.URL(resp.Request.URL)
+	}
+	e.Body, e.Problem = readErrorBody(resp)
+	resp.Body.Close()
+	return e
+}
+
+// readErrorBody returns the start of resp's body and, for problem details
+// responses, the decoded problem
+func readErrorBody(resp *http.Response) ([]byte, *utils.ProblemDetails) {
+	limit := int64(maxErrorBodySnippet)
+	if utils.IsProblemContentType(resp.Header.Get("Content-Type")) {
+		limit = maxProblemBody
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, limit))
+	problem := utils.ParseProblemDetails(resp.Header, body)
+	return body[:min(len(body), maxErrorBodySnippet)], problem
+}
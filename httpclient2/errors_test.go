@@ -0,0 +1,49 @@
+package httpclient2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"httpclient/httpclient/httpmock"
+)
+
+func TestAPIErrorRedactsQueryCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string]string
+		// want must appear in the error message
+		want string
+	}{
+		{name: "api key", want: "api_key=****"},
+		{name: "access token", query: map[string]string{"access_token": "secret"}, want: "access_token=****"},
+		{name: "other parameters kept", query: map[string]string{"page": "2"}, want: "page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/items").Reply(http.StatusForbidden, "denied")
+			c := New(
+				WithBaseURL("http://api.test"),
+				WithTransport(mock),
+				WithAPIKey("secret", "query"),
+				WithErrorOnNon2xx(),
+			)
+
+			_, err := c.Do(context.Background(), Request{Method: http.MethodGet, Path: "/items", Query: tt.query})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			msg := apiErr.Error()
+			if strings.Contains(msg, "secret") {
+				t.Errorf("error leaks the credential: %s", msg)
+			}
+			if !strings.Contains(msg, tt.want) {
+				t.Errorf("error = %s, want it to contain %q", msg, tt.want)
+			}
+		})
+	}
+}
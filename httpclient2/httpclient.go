@@ -6,10 +6,17 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"httpclient/metrics"
+	"httpclient/utils"
 	"io"
+	"maps"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -34,9 +41,37 @@ type Client struct {
 	defaultHeaders map[string]string
 	authMethod     AuthMethod
 	authConfig     map[string]string
+	errorOnNon2xx  bool
+	metrics        metrics.Recorder
+	signer         Signer
+	bodyTimeout    time.Duration
+	// transportBase is the transport options configure; httpClient wraps
+	// it when a body read timeout is set
+	transportBase http.RoundTripper
+	// transportShared is set while a derived client still uses its
+	// parent's transport; tuning it clones the transport first
+	transportShared bool
+	// transportTuned is set once transport options changed transportBase
+	transportTuned bool
+	// transportErr records a transport option that could not be applied;
+	// New and With panic with it
+	transportErr error
+	validators   []Validator
+	// mu guards baseURL, defaultHeaders, authMethod and authConfig, which
+	// the setters replace at runtime; the maps are never modified in place
+	// once the client is built
+	mu *sync.RWMutex
 }
 
-// New creates a new HTTP client with optional configurations
+// Signer authenticates a request in place, e.g. an *httpsig.Signer or an
+// httpclient.SigV4Signer. body is the complete request body, nil when empty
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// New creates a new HTTP client with optional configurations. It panics when
+// transport options cannot be applied, e.g. WithMaxIdleConns combined with a
+// custom RoundTripper from WithTransport
 func New(options ...ClientOption) *Client {
 	client := &Client{
 		httpClient: &http.Client{
@@ -45,6 +80,7 @@ func New(options ...ClientOption) *Client {
 		defaultHeaders: make(map[string]string),
 		authMethod:     AuthNone,
 		authConfig:     make(map[string]string),
+		mu:             &sync.RWMutex{},
 	}
 
 	// Apply provided options
@@ -52,9 +88,101 @@ func New(options ...ClientOption) *Client {
 		opt(client)
 	}
 
+	client.wrapTransport()
 	return client
 }
 
+// With returns a copy of c with options applied, e.g. another base URL,
+// headers or per-tenant credentials. The copy shares the transport and its
+// connection pool with c unless options tune the transport, such as
+// WithMaxIdleConns or WithInsecureSkipVerify; the copy then gets its own
+// transport and c is left unchanged. Like New, it panics when transport
+// options cannot be applied
+func (c *Client) With(options ...ClientOption) *Client {
+	derived := *c.current()
+	derived.mu = &sync.RWMutex{}
+	httpClient := *c.httpClient
+	derived.httpClient = &httpClient
+	derived.defaultHeaders = maps.Clone(c.defaultHeaders)
+	derived.authConfig = maps.Clone(c.authConfig)
+	derived.validators = slices.Clip(c.validators)
+	derived.transportShared, derived.transportTuned = true, false
+
+	for _, opt := range options {
+		opt(&derived)
+	}
+
+	derived.wrapTransport()
+	return &derived
+}
+
+// wrapTransport installs the base transport on the http.Client, wrapped once
+// for the body read timeout. It runs after the options, so they configure
+// the *http.Transport itself
+func (c *Client) wrapTransport() {
+	if c.transportErr != nil {
+		panic(c.transportErr)
+	}
+	c.httpClient.Transport = c.transportBase
+	if c.bodyTimeout > 0 {
+		c.httpClient.Transport = utils.BodyTimeoutTransport(c.transportBase, c.bodyTimeout)
+	}
+}
+
+// SetBaseURL changes the base URL of later requests, e.g. to switch to
+// another endpoint. It is safe to call while requests are in flight
+func (c *Client) SetBaseURL(baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseURL = baseURL
+}
+
+// SetDefaultHeader sets a default header for later requests; an empty value
+// removes it. It is safe to call while requests are in flight
+func (c *Client) SetDefaultHeader(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	headers := maps.Clone(c.defaultHeaders)
+	if value == "" {
+		delete(headers, name)
+	} else {
+		headers[name] = value
+	}
+	c.defaultHeaders = headers
+}
+
+// SetDefaultHeaders replaces all default headers of later requests. It is
+// safe to call while requests are in flight
+func (c *Client) SetDefaultHeaders(headers map[string]string) {
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultHeaders = headers
+}
+
+// SetBearerToken switches later requests to bearer authentication with
+// token, e.g. after rotating credentials. It is safe to call while requests
+// are in flight
+func (c *Client) SetBearerToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	authConfig := maps.Clone(c.authConfig)
+	authConfig["token"] = token
+	c.authMethod, c.authConfig = AuthBearer, authConfig
+}
+
+// current returns a copy of c with a consistent view of the settings the
+// setters change
+func (c *Client) current() *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshot := *c
+	return &snapshot
+}
+
 // WithBaseURL sets the base URL for all requests
 func WithBaseURL(baseURL string) ClientOption {
 	return func(c *Client) {
@@ -108,11 +236,137 @@ func WithAPIKey(key, location string) ClientOption {
 func WithInsecureSkipVerify(skip bool) ClientOption {
 	return func(c *Client) {
 		if skip {
-			transport := &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			}
-			c.httpClient.Transport = transport
+			c.transport().TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+	}
+}
+
+// WithTransport replaces the underlying transport, e.g. with an httpmock.Mock
+// in tests. Transport tuning options need an *http.Transport; combined with
+// another RoundTripper they make New panic
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		if c.transportTuned {
+			c.transportErr = errors.New("httpclient2: WithTransport discards the settings of earlier transport options")
+		}
+		c.transportBase, c.transportShared = rt, false
+	}
+}
+
+// WithMaxIdleConns limits idle connections across all hosts
+func WithMaxIdleConns(n int) ClientOption {
+	return func(c *Client) {
+		c.transport().MaxIdleConns = n
+	}
+}
+
+// WithMaxIdleConnsPerHost limits idle connections kept per host (net/http default 2)
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(c *Client) {
+		c.transport().MaxIdleConnsPerHost = n
+	}
+}
+
+// WithMaxConnsPerHost limits dialing, active and idle connections per host
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(c *Client) {
+		c.transport().MaxConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections stay in the pool
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport().IdleConnTimeout = timeout
+	}
+}
+
+// WithDisableKeepAlives opens a new connection for every request
+func WithDisableKeepAlives(disable bool) ClientOption {
+	return func(c *Client) {
+		c.transport().DisableKeepAlives = disable
+	}
+}
+
+// WithConnectTimeout bounds dialing a new connection
+func WithConnectTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+		c.transport().DialContext = dialer.DialContext
+	}
+}
+
+// WithTLSHandshakeTimeout bounds the TLS handshake of a new connection
+func WithTLSHandshakeTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport().TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout bounds the wait for response headers after the
+// request is written
+func WithResponseHeaderTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport().ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithBodyReadTimeout bounds reading the response body once headers arrived;
+// later reads fail with utils.ErrBodyReadTimeout
+func WithBodyReadTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.bodyTimeout = timeout
+	}
+}
+
+// transport returns the client's own *http.Transport, cloning
+// http.DefaultTransport on first use so transport options can be combined.
+// A derived client clones its parent's transport instead of changing it
+func (c *Client) transport() *http.Transport {
+	var t *http.Transport
+	switch base := c.transportBase.(type) {
+	case *http.Transport:
+		t = base
+		if c.transportShared {
+			t = base.Clone()
 		}
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	default:
+		// Settings for custom RoundTrippers would be lost; New panics instead
+		c.transportErr = fmt.Errorf("httpclient2: transport options need an *http.Transport, not %T", base)
+		return &http.Transport{}
+	}
+	c.transportBase, c.transportShared, c.transportTuned = t, false, true
+	return t
+}
+
+// WithCookieJar stores response cookies in jar and sends them with later
+// requests; httpclient.NewCookieJar returns a jar that persists them
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *Client) {
+		c.httpClient.Jar = jar
+	}
+}
+
+// WithErrorOnNon2xx makes Do return an *APIError for status codes >= 400
+func WithErrorOnNon2xx() ClientOption {
+	return func(c *Client) {
+		c.errorOnNon2xx = true
+	}
+}
+
+// WithMetrics records request counts and latency with the given recorder
+func WithMetrics(recorder metrics.Recorder) ClientOption {
+	return func(c *Client) {
+		c.metrics = recorder
+	}
+}
+
+// WithSigner signs every request after authentication and headers are applied
+func WithSigner(signer Signer) ClientOption {
+	return func(c *Client) {
+		c.signer = signer
 	}
 }
 
@@ -123,10 +377,20 @@ type Request struct {
 	Headers map[string]string
 	Query   map[string]string
 	Body    interface{}
+	// Cookies are sent next to those of the cookie jar
+	Cookies []*http.Cookie
 }
 
 // Do sends an HTTP request and returns the response
 func (c *Client) Do(ctx context.Context, req Request) (*http.Response, error) {
+	// Use one view of the settings for the whole request
+	c = c.current()
+
+	// Fail fast on requests the validators reject
+	if err := c.validate(&req); err != nil {
+		return nil, err
+	}
+
 	// Construct full URL
 	fullURL, err := c.buildURL(req)
 	if err != nil {
@@ -135,8 +399,9 @@ func (c *Client) Do(ctx context.Context, req Request) (*http.Response, error) {
 
 	// Prepare request body
 	var body io.Reader
+	var jsonBody []byte
 	if req.Body != nil {
-		jsonBody, err := json.Marshal(req.Body)
+		jsonBody, err = json.Marshal(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %v", err)
 		}
@@ -158,21 +423,52 @@ func (c *Client) Do(ctx context.Context, req Request) (*http.Response, error) {
 	for k, v := range req.Headers {
 		httpReq.Header.Set(k, v)
 	}
+	for _, cookie := range req.Cookies {
+		httpReq.AddCookie(cookie)
+	}
 
 	// Apply authentication
 	c.applyAuthentication(httpReq)
 
+	// Sign the final request
+	if c.signer != nil {
+		if err := c.signer.Sign(httpReq, jsonBody); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %v", err)
+		}
+	}
+
 	// Send request
-	return c.httpClient.Do(httpReq)
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if c.metrics != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.metrics.ObserveRequest(httpReq.Method, httpReq.URL.Host, status, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.errorOnNon2xx && resp.StatusCode >= 400 {
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
 }
 
 // buildURL constructs the full URL with base URL and query parameters
 func (c *Client) buildURL(req Request) (string, error) {
-	// Combine base URL with request path
-	fullURL := c.baseURL + req.Path
-
-	// Parse the URL
-	parsedURL, err := url.Parse(fullURL)
+	// Combine base URL with request path, keeping any base path prefix
+	var base *url.URL
+	if c.baseURL != "" {
+		var err error
+		if base, err = url.Parse(c.baseURL); err != nil {
+			return "", err
+		}
+	}
+	parsedURL, err := utils.JoinURL(base, req.Path)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,220 @@
+package httpclient2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"httpclient/httpclient/httpmock"
+	"httpclient/utils"
+)
+
+// slowBody delivers its content after delay, failing early when ctx ends.
+type slowBody struct {
+	ctx   context.Context
+	delay time.Duration
+	done  bool
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if b.done {
+		return 0, io.EOF
+	}
+	select {
+	case <-time.After(b.delay):
+		b.done = true
+		return copy(p, "ok"), nil
+	case <-b.ctx.Done():
+		return 0, b.ctx.Err()
+	}
+}
+
+func (b *slowBody) Close() error { return nil }
+
+func TestWithBodyReadTimeout(t *testing.T) {
+	const slow = 100 * time.Millisecond
+	tests := []struct {
+		name    string
+		parent  []ClientOption
+		derived []ClientOption
+		wantErr error
+	}{
+		{
+			name:    "inherited timeout",
+			parent:  []ClientOption{WithBodyReadTimeout(20 * time.Millisecond)},
+			wantErr: utils.ErrBodyReadTimeout,
+		},
+		{
+			name:    "longer timeout replaces the parent's",
+			parent:  []ClientOption{WithBodyReadTimeout(20 * time.Millisecond)},
+			derived: []ClientOption{WithBodyReadTimeout(time.Second)},
+		},
+		{
+			name:    "timeout added to the copy only",
+			derived: []ClientOption{WithBodyReadTimeout(20 * time.Millisecond)},
+			wantErr: utils.ErrBodyReadTimeout,
+		},
+		{
+			name:    "timeout removed from the copy",
+			parent:  []ClientOption{WithBodyReadTimeout(20 * time.Millisecond)},
+			derived: []ClientOption{WithBodyReadTimeout(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.New()
+			mock.On(http.MethodGet, "/slow").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     make(http.Header),
+					Body:       &slowBody{ctx: req.Context(), delay: slow},
+					Request:    req,
+				}, nil
+			})
+			parent := New(append([]ClientOption{WithBaseURL("http://api.test"), WithTransport(mock)}, tt.parent...)...)
+			c := parent.With(tt.derived...)
+
+			resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, Path: "/slow"})
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			defer resp.Body.Close()
+			if _, err := io.ReadAll(resp.Body); !errors.Is(err, tt.wantErr) {
+				t.Errorf("read error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithTransportOptions(t *testing.T) {
+	parent := New(WithBodyReadTimeout(time.Second), WithMaxIdleConnsPerHost(4))
+	parentTransport := parent.transportBase.(*http.Transport)
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+		// check inspects the derived client's transport
+		check func(t *testing.T, derived *http.Transport)
+	}{
+		{
+			name: "no transport options share the parent's",
+			check: func(t *testing.T, derived *http.Transport) {
+				if derived != parentTransport {
+					t.Error("derived client has its own transport, want the parent's")
+				}
+			},
+		},
+		{
+			name: "tuning behind the body timeout",
+			opts: []ClientOption{WithMaxIdleConnsPerHost(8)},
+			check: func(t *testing.T, derived *http.Transport) {
+				if derived.MaxIdleConnsPerHost != 8 {
+					t.Errorf("MaxIdleConnsPerHost = %d, want 8", derived.MaxIdleConnsPerHost)
+				}
+			},
+		},
+		{
+			name: "insecure copy",
+			opts: []ClientOption{WithInsecureSkipVerify(true)},
+			check: func(t *testing.T, derived *http.Transport) {
+				if derived.TLSClientConfig == nil || !derived.TLSClientConfig.InsecureSkipVerify {
+					t.Error("derived client verifies certificates, want InsecureSkipVerify")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			derived := parent.With(tt.opts...)
+			transport, ok := derived.transportBase.(*http.Transport)
+			if !ok {
+				t.Fatalf("derived base transport = %T, want *http.Transport", derived.transportBase)
+			}
+			tt.check(t, transport)
+
+			// The parent is never changed by its copies
+			if parent.transportBase != parentTransport || parentTransport.MaxIdleConnsPerHost != 4 {
+				t.Error("parent transport changed")
+			}
+			if tls := parentTransport.TLSClientConfig; tls != nil && tls.InsecureSkipVerify {
+				t.Error("parent skips certificate verification")
+			}
+		})
+	}
+}
+
+func TestTransportOptionsOnCustomRoundTripper(t *testing.T) {
+	mock := httpmock.New()
+	tests := []struct {
+		name  string
+		build func()
+	}{
+		{name: "option after WithTransport", build: func() { New(WithTransport(mock), WithMaxIdleConns(5)) }},
+		{name: "WithTransport after option", build: func() { New(WithMaxIdleConns(5), WithTransport(mock)) }},
+		{name: "derived client", build: func() { New(WithTransport(mock)).With(WithInsecureSkipVerify(true)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic, want one for the lost transport settings")
+				}
+			}()
+			tt.build()
+		})
+	}
+}
+
+func TestSettersDuringRequests(t *testing.T) {
+	mock := httpmock.New()
+	mock.On(http.MethodGet, "/items").Reply(http.StatusOK, "[]")
+	c := New(WithBaseURL("http://a.test"), WithTransport(mock), WithBearerToken("t0"))
+
+	setters := []func(i int){
+		func(i int) { c.SetBaseURL(fmt.Sprintf("http://%c.test", 'a'+i%2)) },
+		func(i int) { c.SetDefaultHeader("X-Attempt", strconv.Itoa(i)) },
+		func(i int) { c.SetDefaultHeaders(map[string]string{"X-Batch": strconv.Itoa(i)}) },
+		func(i int) { c.SetBearerToken("t" + strconv.Itoa(i)) },
+	}
+	var wg sync.WaitGroup
+	for _, set := range setters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				set(i)
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, Path: "/items"})
+				if err != nil {
+					t.Errorf("Do: %v", err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, req := range mock.Calls() {
+		if host := req.URL.Host; host != "a.test" && host != "b.test" {
+			t.Errorf("request to %s, want a.test or b.test", host)
+		}
+		if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer t") {
+			t.Errorf("Authorization = %q, want a bearer token", auth)
+		}
+	}
+}
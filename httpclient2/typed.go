@@ -0,0 +1,60 @@
+package httpclient2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SendJSON sends req (its Body marshaled as JSON) and decodes the response
+// into T. Responses with status >= 400 return an *APIError; 204 and empty
+// bodies yield the zero T. The returned response has its body closed
+func SendJSON[T any](ctx context.Context, c *Client, req Request) (T, *http.Response, error) {
+	var out T
+
+	headers := make(map[string]string, len(req.Headers)+2)
+	headers["Accept"] = "application/json"
+	if req.Body != nil {
+		headers["Content-Type"] = "application/json"
+	}
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	req.Headers = headers
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return out, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return out, resp, newAPIError(resp)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return out, resp, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode == http.StatusNoContent || len(bytes.TrimSpace(data)) == 0 {
+		return out, resp, nil
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, resp, fmt.Errorf("failed to decode %T response: %w", out, err)
+	}
+	return out, resp, nil
+}
+
+// GetJSON sends a GET to path with the given query and decodes the response into T
+func GetJSON[T any](ctx context.Context, c *Client, path string, query map[string]string) (T, error) {
+	out, _, err := SendJSON[T](ctx, c, Request{Method: http.MethodGet, Path: path, Query: query})
+	return out, err
+}
+
+// PostJSON sends body as JSON in a POST to path and decodes the response into T
+func PostJSON[T any](ctx context.Context, c *Client, path string, body interface{}) (T, error) {
+	out, _, err := SendJSON[T](ctx, c, Request{Method: http.MethodPost, Path: path, Body: body})
+	return out, err
+}
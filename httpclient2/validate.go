@@ -0,0 +1,90 @@
+package httpclient2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ErrInvalidRequest matches, via errors.Is, every *ValidationError
+var ErrInvalidRequest = errors.New("invalid request")
+
+// Validator checks a request before it is built; a non-nil error fails Do
+// without sending anything
+type Validator func(req *Request) error
+
+// ValidationError is returned by Do when a Validator rejects a request
+type ValidationError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid request %s %s: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+func (e *ValidationError) Is(target error) bool { return target == ErrInvalidRequest }
+
+// WithValidators runs validators, in order, before every request
+func WithValidators(validators ...Validator) ClientOption {
+	return func(c *Client) {
+		c.validators = append(c.validators, validators...)
+	}
+}
+
+// RequireBody rejects requests without a Body for the given methods, POST and
+// PUT when none are given
+func RequireBody(methods ...string) Validator {
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPut}
+	}
+	return func(req *Request) error {
+		if req.Body == nil && slices.ContainsFunc(methods, func(m string) bool { return strings.EqualFold(m, req.Method) }) {
+			return fmt.Errorf("%s requires a body", strings.ToUpper(req.Method))
+		}
+		return nil
+	}
+}
+
+// AllowMethods rejects requests whose method is not listed
+func AllowMethods(methods ...string) Validator {
+	return func(req *Request) error {
+		if !slices.ContainsFunc(methods, func(m string) bool { return strings.EqualFold(m, req.Method) }) {
+			return fmt.Errorf("method %q is not allowed", req.Method)
+		}
+		return nil
+	}
+}
+
+// ValidHeaders rejects header names and values that are not valid HTTP, such
+// as values carrying CR or LF from untrusted input, which could inject headers
+func ValidHeaders() Validator {
+	return func(req *Request) error {
+		for name, value := range req.Headers {
+			if !httpguts.ValidHeaderFieldName(name) {
+				return fmt.Errorf("header %q: invalid name", name)
+			}
+			if !httpguts.ValidHeaderFieldValue(value) {
+				return fmt.Errorf("header %q: value contains control characters", name)
+			}
+		}
+		return nil
+	}
+}
+
+// validate runs the client's validators on req
+func (c *Client) validate(req *Request) error {
+	for _, validator := range c.validators {
+		if err := validator(req); err != nil {
+			return &ValidationError{Method: req.Method, Path: req.Path, Err: err}
+		}
+	}
+	return nil
+}
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
+	"net/http"
 	"net/url"
 	"time"
 
+	"httpclient/metrics"
+	"httpclient/utils"
 	"log/slog"
 
 	"github.com/go-resty/resty/v2"
@@ -24,6 +28,45 @@ type ClientConfig struct {
 	RetryBackoff      time.Duration
 	Logger            *slog.Logger
 	HTTPTimeout       time.Duration
+	// MaxRetryAfter caps delays requested by Retry-After headers on 429 and
+	// 503 responses (default 30s).
+	MaxRetryAfter time.Duration
+	// Metrics, when set, records request counts, latency and retries.
+	Metrics metrics.Recorder
+	// Redaction for logs. RedactHeaders is case-insensitive and defaults to
+	// utils.DefaultRedactHeaders when nil; RedactBodyJSONPaths uses dotted
+	// paths with "*" wildcards, e.g. "user.password" or "items.*.token".
+	RedactHeaders       []string
+	RedactQueryParams   []string
+	RedactBodyJSONPaths []string
+	// Transport, when set, replaces the underlying transport, e.g. with an
+	// httpmock.Mock in tests.
+	Transport http.RoundTripper
+	// Signer, when set, signs every attempt right before it is sent, e.g. an
+	// *httpsig.Signer or an httpclient.SigV4Signer.
+	Signer Signer
+	// Timeout stages. ConnectTimeout bounds dialing, TLSHandshakeTimeout the
+	// handshake, ResponseHeaderTimeout the wait for response headers and
+	// BodyReadTimeout reading the body once they arrived, failing it with
+	// utils.ErrBodyReadTimeout. HTTPTimeout still bounds each attempt. The
+	// first three only apply to an *http.Transport.
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	BodyReadTimeout       time.Duration
+	// MaxLogBodyBytes, if > 0, truncates logged request and response bodies
+	// to this many bytes. Binary and encoded bodies are always logged as a
+	// placeholder with their type and size.
+	MaxLogBodyBytes int
+	// ContextAttrs, when set, extracts attributes such as a trace ID, tenant
+	// or user ID from the request context, added to every request log line.
+	ContextAttrs func(ctx context.Context) []slog.Attr
+}
+
+// Signer authenticates a request in place. body is the complete request body,
+// nil when there is none.
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
 }
 
 // RequestOptions allows per-request customizations.
@@ -45,6 +88,9 @@ type CommonHTTPClient struct {
 	disableLogHeaders bool
 	disableLogQuery   bool
 	logger            *slog.Logger
+	redactor          *utils.Redactor
+	maxLogBody        int
+	contextAttrs      func(ctx context.Context) []slog.Attr
 }
 
 // NewCommonHTTPClient creates a new client with the provided config.
@@ -54,6 +100,9 @@ func NewCommonHTTPClient(cfg ClientConfig) *CommonHTTPClient {
 	}
 
 	client := resty.New()
+	if transport := cfg.transport(); transport != nil {
+		client.SetTransport(transport)
+	}
 
 	if cfg.HTTPTimeout > 0 {
 		client.SetTimeout(cfg.HTTPTimeout)
@@ -61,12 +110,25 @@ func NewCommonHTTPClient(cfg ClientConfig) *CommonHTTPClient {
 		client.SetTimeout(30 * time.Second)
 	}
 
+	if cfg.MaxRetryAfter <= 0 {
+		cfg.MaxRetryAfter = 30 * time.Second
+	}
+
 	if cfg.MaxRetries > 0 {
 		client.
 			SetRetryCount(cfg.MaxRetries).
 			SetRetryWaitTime(cfg.RetryBackoff).
+			SetRetryMaxWaitTime(max(cfg.MaxRetryAfter, cfg.RetryBackoff)).
+			AddRetryCondition(func(resp *resty.Response, err error) bool {
+				return resp != nil && isRetryAfterStatus(resp.StatusCode())
+			}).
 			SetRetryAfter(func(client *resty.Client, resp *resty.Response) (time.Duration, error) {
-				// Simple retry after logic: always wait RetryBackoff between tries
+				// Honor Retry-After when present, otherwise wait RetryBackoff between tries
+				if resp != nil && isRetryAfterStatus(resp.StatusCode()) {
+					if d, ok := utils.ParseRetryAfter(resp.Header().Get("Retry-After"), time.Now()); ok {
+						return min(d, cfg.MaxRetryAfter), nil
+					}
+				}
 				return cfg.RetryBackoff, nil
 			})
 	}
@@ -84,6 +146,9 @@ func NewCommonHTTPClient(cfg ClientConfig) *CommonHTTPClient {
 		disableLogHeaders: cfg.DisableLogHeaders,
 		disableLogQuery:   cfg.DisableLogQuery,
 		logger:            cfg.Logger,
+		redactor:          utils.NewRedactor(cfg.RedactHeaders, cfg.RedactQueryParams, cfg.RedactBodyJSONPaths),
+		maxLogBody:        cfg.MaxLogBodyBytes,
+		contextAttrs:      cfg.ContextAttrs,
 	}
 
 	// Set hooks for logging
@@ -97,9 +162,84 @@ func NewCommonHTTPClient(cfg ClientConfig) *CommonHTTPClient {
 		return nil
 	})
 
+	// Sign the raw request, which resty builds anew for every retry
+	if cfg.Signer != nil {
+		commonClient.client.SetPreRequestHook(func(c *resty.Client, r *http.Request) error {
+			var body []byte
+			if r.GetBody != nil {
+				rc, err := r.GetBody()
+				if err != nil {
+					return err
+				}
+				defer rc.Close()
+				if body, err = io.ReadAll(rc); err != nil {
+					return err
+				}
+			}
+			return cfg.Signer.Sign(r, body)
+		})
+	}
+
+	// Set hooks for metrics
+	if cfg.Metrics != nil {
+		commonClient.client.OnSuccess(func(c *resty.Client, r *resty.Response) {
+			observeResponse(cfg.Metrics, r)
+		})
+		commonClient.client.OnInvalid(func(r *resty.Request, err error) {
+			cfg.Metrics.ObserveRequest(r.Method, requestHost(r), 0, 0)
+		})
+		commonClient.client.OnError(func(r *resty.Request, err error) {
+			var respErr *resty.ResponseError
+			if errors.As(err, &respErr) && respErr.Response != nil {
+				observeResponse(cfg.Metrics, respErr.Response)
+				return
+			}
+			cfg.Metrics.ObserveRequest(r.Method, requestHost(r), 0, time.Since(r.Time))
+		})
+		commonClient.client.AddRetryHook(func(r *resty.Response, err error) {
+			if r != nil && r.Request != nil {
+				cfg.Metrics.ObserveRetry(r.Request.Method, requestHost(r.Request))
+			}
+		})
+	}
+
 	return commonClient
 }
 
+// transport returns cfg.Transport with the timeout stages applied, or nil to
+// keep resty's default.
+func (cfg *ClientConfig) transport() http.RoundTripper {
+	rt := cfg.Transport
+	if cfg.ConnectTimeout > 0 || cfg.TLSHandshakeTimeout > 0 || cfg.ResponseHeaderTimeout > 0 {
+		var t *http.Transport
+		switch base := rt.(type) {
+		case nil:
+			t = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			t = base.Clone()
+		default:
+			cfg.Logger.Warn("Cannot apply transport timeouts to custom RoundTripper")
+		}
+		if t != nil {
+			if cfg.ConnectTimeout > 0 {
+				dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+				t.DialContext = dialer.DialContext
+			}
+			if cfg.TLSHandshakeTimeout > 0 {
+				t.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+			}
+			if cfg.ResponseHeaderTimeout > 0 {
+				t.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+			}
+			rt = t
+		}
+	}
+	if cfg.BodyReadTimeout > 0 {
+		rt = utils.BodyTimeoutTransport(rt, cfg.BodyReadTimeout)
+	}
+	return rt
+}
+
 // Do executes an HTTP request with the given options.
 func (c *CommonHTTPClient) Do(ctx context.Context, opts RequestOptions) (*resty.Response, error) {
 	req := c.client.R().SetContext(ctx)
@@ -138,34 +278,61 @@ func (c *CommonHTTPClient) Do(ctx context.Context, opts RequestOptions) (*resty.
 		req.SetBody(bodyBytes)
 	}
 
+	// Join the path onto the base URL ourselves so base path prefixes survive
+	reqURL, err := utils.JoinURL(c.baseURL, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	target := reqURL.String()
+
 	// Perform request by Method
 	var resp *resty.Response
-	var err error
 	switch opts.Method {
 	case "GET":
-		resp, err = req.Get(opts.Path)
+		resp, err = req.Get(target)
 	case "POST":
-		resp, err = req.Post(opts.Path)
+		resp, err = req.Post(target)
 	case "PUT":
-		resp, err = req.Put(opts.Path)
+		resp, err = req.Put(target)
 	case "DELETE":
-		resp, err = req.Delete(opts.Path)
+		resp, err = req.Delete(target)
 	case "PATCH":
-		resp, err = req.Patch(opts.Path)
+		resp, err = req.Patch(target)
 	case "HEAD":
-		resp, err = req.Head(opts.Path)
+		resp, err = req.Head(target)
 	default:
 		return nil, errors.New("unsupported method")
 	}
 
 	if err != nil {
-		c.logger.Error("HTTP request failed", slog.String("url", resp.Request.URL), slog.Any("error", err))
+		c.log(ctx, slog.LevelError, "HTTP request failed", slog.String("url", resp.Request.URL), slog.Any("error", err))
 		return nil, err
 	}
 
 	return resp, nil
 }
 
+// observeResponse records a completed request with the metrics recorder.
+func observeResponse(recorder metrics.Recorder, r *resty.Response) {
+	recorder.ObserveRequest(r.Request.Method, requestHost(r.Request), r.StatusCode(), time.Since(r.Request.Time))
+}
+
+// requestHost returns the host a resty request was sent to.
+func requestHost(r *resty.Request) string {
+	if r.RawRequest != nil {
+		return r.RawRequest.URL.Host
+	}
+	if u, err := url.Parse(r.URL); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// isRetryAfterStatus reports whether a status code may carry Retry-After.
+func isRetryAfterStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
 // logRequest logs request details before sending it.
 func (c *CommonHTTPClient) logRequest(r *resty.Request) {
 	var headers map[string][]string
@@ -175,11 +342,12 @@ func (c *CommonHTTPClient) logRequest(r *resty.Request) {
 		for k, v := range r.Header {
 			headers[k] = v
 		}
+		headers = c.redactor.Headers(headers)
 	}
 
 	queryStr := ""
 	if !c.disableLogQuery {
-		queryValues := r.QueryParam
+		queryValues := c.redactor.QueryValues(r.QueryParam)
 		if queryValues != nil {
 			queryBytes, _ := json.Marshal(queryValues)
 			queryStr = string(queryBytes)
@@ -190,15 +358,15 @@ func (c *CommonHTTPClient) logRequest(r *resty.Request) {
 	if !c.disableLogBody && r.RawRequest != nil && r.RawRequest.Body != nil {
 		// We already have the body in r.body (bytes)
 		if body, ok := r.Body.(string); ok {
-			bodyStr = body
+			bodyStr = utils.LogBody([]byte(body), r.Header, c.maxLogBody, c.redactor)
 		} else if b, ok := r.Body.([]byte); ok {
-			bodyStr = string(b)
+			bodyStr = utils.LogBody(b, r.Header, c.maxLogBody, c.redactor)
 		}
 	}
 
-	c.logger.Info("Outgoing request",
+	c.log(r.Context(), slog.LevelInfo, "Outgoing request",
 		slog.String("method", r.Method),
-		slog.String("url", r.URL),
+		slog.String("url", c.redactURL(r.URL)),
 		slog.String("query", queryStr),
 		slog.Any("headers", headers),
 		slog.String("body", bodyStr),
@@ -213,20 +381,38 @@ func (c *CommonHTTPClient) logResponse(resp *resty.Response) {
 		for k, v := range resp.Header() {
 			headers[k] = v
 		}
+		headers = c.redactor.Headers(headers)
 	}
 
 	var bodyStr string
 	if !c.disableLogBody && resp.Body() != nil {
-		bodyStr = string(resp.Body())
+		bodyStr = utils.LogBody(resp.Body(), resp.Header(), c.maxLogBody, c.redactor)
 	}
 
-	c.logger.Info("Incoming response",
+	c.log(resp.Request.Context(), slog.LevelInfo, "Incoming response",
 		slog.Int("status_code", resp.StatusCode()),
 		slog.Any("headers", headers),
 		slog.String("body", bodyStr),
 	)
 }
 
+// log writes a log line with the attributes extracted from ctx appended.
+func (c *CommonHTTPClient) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.contextAttrs != nil {
+		attrs = append(attrs, c.contextAttrs(ctx)...)
+	}
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// redactURL masks sensitive query parameters embedded in a raw URL.
+func (c *CommonHTTPClient) redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return c.redactor.URL(u)
+}
+
 // Example of an input/output processor
 func DecodeJSONResponse(resp *resty.Response, v interface{}) error {
 	if resp.Body() == nil {
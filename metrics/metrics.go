@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Recorder receives request outcomes from the clients. Metrics implements it
+// with Prometheus collectors; tests and other backends can provide their own.
+type Recorder interface {
+	// ObserveRequest records a finished logical request (including retries).
+	// A status of 0 means the request failed without a response.
+	ObserveRequest(method, host string, status int, duration time.Duration)
+	// ObserveRetry records one retry attempt.
+	ObserveRetry(method, host string)
+}
+
+// ChecksumRecorder is optionally implemented by a Recorder to count response
+// checksum verifications. Outcome is "ok" or "mismatch".
+type ChecksumRecorder interface {
+	ObserveChecksum(algorithm, outcome string)
+}
+
+// RouteRecorder is optionally implemented by a Recorder to record requests
+// by route template, e.g. "/users/{id}", which unlike raw paths keeps label
+// cardinality bounded.
+type RouteRecorder interface {
+	ObserveRoute(method, route string, status int, duration time.Duration)
+}
+
+// BytesRecorder is optionally implemented by a Recorder to count the request
+// and response body bytes of finished requests, including bodies resent on
+// retries.
+type BytesRecorder interface {
+	ObserveBytes(method, host string, sent, received int64)
+}
+
+// WebhookRecorder is optionally implemented by a Recorder to count webhook
+// deliveries. Attempts are labeled by status (0 for transport errors) and
+// finished deliveries by outcome, "delivered" or "dead_letter".
+type WebhookRecorder interface {
+	ObserveWebhookAttempt(event string, status int)
+	ObserveWebhookDelivery(event, outcome string)
+}
+
+// ConcurrencyRecorder is optionally implemented by a Recorder to track the
+// client's in-flight limits. Waits are labeled by outcome, "acquired" or
+// "timeout"; AddInFlight adjusts the number of requests holding a slot.
+type ConcurrencyRecorder interface {
+	ObserveInFlightWait(host, outcome string, wait time.Duration)
+	AddInFlight(host string, delta int)
+}
+
+// Metrics holds the Prometheus collectors for outgoing HTTP requests.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+	checks   *prometheus.CounterVec
+	routes   *prometheus.HistogramVec
+	sent     *prometheus.CounterVec
+	received *prometheus.CounterVec
+	hooks    *prometheus.CounterVec
+	hookRuns *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+	waits    *prometheus.HistogramVec
+}
+
+// New creates the collectors under the given namespace (e.g. "myservice"),
+// producing <namespace>_http_client_requests_total and friends.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "requests_total",
+			Help:      "Outgoing HTTP requests by method, host and status.",
+		}, []string{"method", "host", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "Outgoing HTTP request latency, including retries.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "host"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "retries_total",
+			Help:      "Retried outgoing HTTP request attempts.",
+		}, []string{"method", "host"}),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "checksum_verifications_total",
+			Help:      "Response body checksum verifications by algorithm and outcome.",
+		}, []string{"algorithm", "outcome"}),
+		routes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "route_request_duration_seconds",
+			Help:      "Outgoing HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_bytes_total",
+			Help:      "Request body bytes sent, by method and host.",
+		}, []string{"method", "host"}),
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "response_bytes_total",
+			Help:      "Response body bytes received, by method and host.",
+		}, []string{"method", "host"}),
+		hooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "webhook_attempts_total",
+			Help:      "Webhook delivery attempts by event and status.",
+		}, []string{"event", "status"}),
+		hookRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "webhook_deliveries_total",
+			Help:      "Finished webhook deliveries by event and outcome.",
+		}, []string{"event", "outcome"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "in_flight_requests",
+			Help:      "Outgoing HTTP requests holding an in-flight slot, by host.",
+		}, []string{"host"}),
+		waits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "in_flight_wait_seconds",
+			Help:      "Time spent waiting for an in-flight slot, by host and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "outcome"}),
+	}
+}
+
+// Register registers all collectors with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range m.Collectors() {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Collectors returns the underlying collectors, e.g. for MustRegister.
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requests, m.duration, m.retries, m.checks, m.routes, m.sent, m.received, m.hooks, m.hookRuns, m.inFlight, m.waits}
+}
+
+// ObserveRequest implements Recorder.
+func (m *Metrics) ObserveRequest(method, host string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, host, statusLabel(status)).Inc()
+	m.duration.WithLabelValues(method, host).Observe(duration.Seconds())
+}
+
+// ObserveRetry implements Recorder.
+func (m *Metrics) ObserveRetry(method, host string) {
+	m.retries.WithLabelValues(method, host).Inc()
+}
+
+// ObserveRoute implements RouteRecorder.
+func (m *Metrics) ObserveRoute(method, route string, status int, duration time.Duration) {
+	m.routes.WithLabelValues(method, route, statusLabel(status)).Observe(duration.Seconds())
+}
+
+// ObserveBytes implements BytesRecorder.
+func (m *Metrics) ObserveBytes(method, host string, sent, received int64) {
+	m.sent.WithLabelValues(method, host).Add(float64(sent))
+	m.received.WithLabelValues(method, host).Add(float64(received))
+}
+
+// ObserveChecksum implements ChecksumRecorder.
+func (m *Metrics) ObserveChecksum(algorithm, outcome string) {
+	m.checks.WithLabelValues(algorithm, outcome).Inc()
+}
+
+// ObserveWebhookAttempt implements WebhookRecorder.
+func (m *Metrics) ObserveWebhookAttempt(event string, status int) {
+	m.hooks.WithLabelValues(event, statusLabel(status)).Inc()
+}
+
+// ObserveWebhookDelivery implements WebhookRecorder.
+func (m *Metrics) ObserveWebhookDelivery(event, outcome string) {
+	m.hookRuns.WithLabelValues(event, outcome).Inc()
+}
+
+// ObserveInFlightWait implements ConcurrencyRecorder.
+func (m *Metrics) ObserveInFlightWait(host, outcome string, wait time.Duration) {
+	m.waits.WithLabelValues(host, outcome).Observe(wait.Seconds())
+}
+
+// AddInFlight implements ConcurrencyRecorder.
+func (m *Metrics) AddInFlight(host string, delta int) {
+	m.inFlight.WithLabelValues(host).Add(float64(delta))
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		return "error"
+	}
+	return strconv.Itoa(status)
+}
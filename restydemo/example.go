@@ -20,6 +20,9 @@ func Example() {
 	disableLogHeaders := false
 	disableLogQuery := false
 
+	// Mask credentials before they reach the logs
+	redactor := utils.NewRedactor(nil, []string{"api_key"}, []string{"password"})
+
 	// Initialize slog logger
 	logger := slog.New(utils.NewPrettyJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
@@ -34,12 +37,12 @@ func Example() {
 		SetRetryWaitTime(1 * time.Second).
 		// Before sending the request, log request details
 		OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
-			logRequest(logger, r, disableLogBody, disableLogHeaders, disableLogQuery)
+			logRequest(logger, redactor, r, disableLogBody, disableLogHeaders, disableLogQuery)
 			return nil
 		}).
 		// After receiving the response, log response details
 		OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
-			logResponse(logger, resp, disableLogBody, disableLogHeaders)
+			logResponse(logger, redactor, resp, disableLogBody, disableLogHeaders)
 			return nil
 		})
 
@@ -82,19 +85,20 @@ func Example() {
 }
 
 // logRequest logs request details before sending it.
-func logRequest(logger *slog.Logger, r *resty.Request, disableLogBody, disableLogHeaders, disableLogQuery bool) {
+func logRequest(logger *slog.Logger, redactor *utils.Redactor, r *resty.Request, disableLogBody, disableLogHeaders, disableLogQuery bool) {
 	var headers map[string][]string
 	if !disableLogHeaders {
 		headers = make(map[string][]string)
 		for k, v := range r.Header {
 			headers[k] = v
 		}
+		headers = redactor.Headers(headers)
 	}
 
 	queryStr := ""
 	if !disableLogQuery && r.RawRequest != nil {
 		queryValues := r.RawRequest.URL.Query()
-		queryMap := map[string][]string(queryValues)
+		queryMap := redactor.QueryValues(queryValues)
 		qBytes, _ := json.Marshal(queryMap)
 		queryStr = string(qBytes)
 	}
@@ -114,26 +118,27 @@ func logRequest(logger *slog.Logger, r *resty.Request, disableLogBody, disableLo
 
 	logger.Info("Outgoing request",
 		slog.String("method", r.Method),
-		slog.String("url", r.URL),
+		slog.String("url", redactURL(redactor, r.URL)),
 		slog.String("query", queryStr),
 		slog.Any("headers", headers),
-		slog.String("body", bodyStr),
+		slog.String("body", redactor.Body(bodyStr)),
 	)
 }
 
 // logResponse logs response details after receiving it.
-func logResponse(logger *slog.Logger, resp *resty.Response, disableLogBody, disableLogHeaders bool) {
+func logResponse(logger *slog.Logger, redactor *utils.Redactor, resp *resty.Response, disableLogBody, disableLogHeaders bool) {
 	var headers map[string][]string
 	if !disableLogHeaders {
 		headers = make(map[string][]string)
 		for k, v := range resp.Header() {
 			headers[k] = v
 		}
+		headers = redactor.Headers(headers)
 	}
 
 	var bodyStr string
 	if !disableLogBody && resp.Body() != nil {
-		bodyStr = string(resp.Body())
+		bodyStr = redactor.Body(string(resp.Body()))
 	}
 
 	logger.Info("Incoming response",
@@ -142,3 +147,12 @@ func logResponse(logger *slog.Logger, resp *resty.Response, disableLogBody, disa
 		slog.String("body", bodyStr),
 	)
 }
+
+// redactURL masks sensitive query parameters embedded in a raw URL.
+func redactURL(redactor *utils.Redactor, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return redactor.URL(u)
+}
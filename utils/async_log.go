@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncHandlerOptions configures NewAsyncHandler.
+type AsyncHandlerOptions struct {
+	// BufferSize is the number of records queued for writing (default 1024).
+	BufferSize int
+	// DropOldest discards the oldest queued record when the buffer is full.
+	// By default logging blocks until there is room.
+	DropOldest bool
+	// OnError receives errors returned by the wrapped handler, which are
+	// otherwise discarded.
+	OnError func(error)
+}
+
+// AsyncHandler is a slog.Handler queuing records in a buffer and passing them
+// to another handler on a background goroutine, keeping slow writers off the
+// request path. Flush waits for the queued records to be written; Close does
+// so and stops the goroutine, after which records are written synchronously.
+type AsyncHandler struct {
+	next slog.Handler
+	q    *asyncQueue
+}
+
+// asyncQueue is shared by an AsyncHandler and the handlers derived from it
+// with WithAttrs and WithGroup.
+type asyncQueue struct {
+	opts    AsyncHandlerOptions
+	records chan asyncRecord
+	done    chan struct{}
+	dropped atomic.Int64
+
+	// closeMu guards sends on records against Close
+	closeMu   sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+
+	// mu guards pending and idle, which is closed when pending drops to 0
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{}
+}
+
+type asyncRecord struct {
+	h   slog.Handler
+	ctx context.Context
+	r   slog.Record
+}
+
+// NewAsyncHandler returns an AsyncHandler writing to next. opts may be nil.
+func NewAsyncHandler(next slog.Handler, opts *AsyncHandlerOptions) *AsyncHandler {
+	q := &asyncQueue{done: make(chan struct{})}
+	if opts != nil {
+		q.opts = *opts
+	}
+	if q.opts.BufferSize <= 0 {
+		q.opts.BufferSize = 1024
+	}
+	q.records = make(chan asyncRecord, q.opts.BufferSize)
+	go q.run()
+	return &AsyncHandler{next: next, q: q}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	q := h.q
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	if q.closed {
+		return h.next.Handle(ctx, r)
+	}
+	// The record outlives the call and the caller's cancellation
+	rec := asyncRecord{h: h.next, ctx: context.WithoutCancel(ctx), r: r.Clone()}
+	q.add(1)
+	if !q.opts.DropOldest {
+		q.records <- rec
+		return nil
+	}
+	for {
+		select {
+		case q.records <- rec:
+			return nil
+		default:
+		}
+		select {
+		case <-q.records:
+			q.dropped.Add(1)
+			q.add(-1)
+		default:
+		}
+	}
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{next: h.next.WithAttrs(attrs), q: h.q}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{next: h.next.WithGroup(name), q: h.q}
+}
+
+// Flush waits until the records queued so far are written or ctx is done.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	q := h.q
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	idle := q.idle
+	q.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the queued records and stops the background goroutine. It is
+// shared with the handlers derived from h and safe to call more than once.
+func (h *AsyncHandler) Close() error {
+	q := h.q
+	q.closeOnce.Do(func() {
+		q.closeMu.Lock()
+		q.closed = true
+		close(q.records)
+		q.closeMu.Unlock()
+	})
+	<-q.done
+	return nil
+}
+
+// Dropped returns the number of records discarded because the buffer was
+// full.
+func (h *AsyncHandler) Dropped() int64 {
+	return h.q.dropped.Load()
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for rec := range q.records {
+		if err := rec.h.Handle(rec.ctx, rec.r); err != nil && q.opts.OnError != nil {
+			q.opts.OnError(err)
+		}
+		q.add(-1)
+	}
+}
+
+// add adjusts the number of records queued or being written.
+func (q *asyncQueue) add(delta int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == 0 && delta > 0 {
+		q.idle = make(chan struct{})
+	}
+	q.pending += delta
+	if q.pending == 0 {
+		close(q.idle)
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBodyReadTimeout is returned by reads of a response body that was not
+// consumed within its body read timeout.
+var ErrBodyReadTimeout = errors.New("response body read timeout exceeded")
+
+// TimeoutBody limits reading body to d from now. Once d passes, abort is
+// called, which should cancel the request's context so a blocked Read
+// returns, and reads fail with ErrBodyReadTimeout. Close stops the timer and
+// calls abort as well.
+func TimeoutBody(body io.ReadCloser, d time.Duration, abort func()) io.ReadCloser {
+	b := &timeoutBody{ReadCloser: body, abort: abort}
+	b.timer = time.AfterFunc(d, func() {
+		b.expired.Store(true)
+		abort()
+	})
+	return b
+}
+
+type timeoutBody struct {
+	io.ReadCloser
+	abort   func()
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.expired.Load() {
+		err = ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.abort()
+	return err
+}
+
+// BodyTimeoutTransport wraps rt (http.DefaultTransport when nil) so every
+// response body must be read within d of its headers arriving.
+func BodyTimeoutTransport(rt http.RoundTripper, d time.Duration) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &bodyTimeoutTransport{base: rt, timeout: d}
+}
+
+type bodyTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *bodyTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = TimeoutBody(resp.Body, t.timeout, cancel)
+	return resp, nil
+}
@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ANSI escape sequences used by the console handler.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// ConsoleHandlerOptions configures NewConsoleHandler.
+type ConsoleHandlerOptions struct {
+	// HandlerOptions sets the minimum level, AddSource and ReplaceAttr as for
+	// the standard library handlers.
+	slog.HandlerOptions
+	// TimeFormat formats record times (default "15:04:05.000").
+	TimeFormat string
+	// MessageWidth pads messages so attributes line up (default 24).
+	MessageWidth int
+	// NoColor disables ANSI colors, e.g. when w is not a terminal.
+	NoColor bool
+}
+
+// NewConsoleHandler returns a slog.Handler writing one colored, human-friendly
+// line per record for local development:
+//
+//	15:04:05.000 INFO  Outgoing request         method=GET url=https://api/users
+//
+// Attributes in groups are prefixed with the group names, e.g. "timings.dns_ms".
+// opts may be nil.
+func NewConsoleHandler(w io.Writer, opts *ConsoleHandlerOptions) slog.Handler {
+	h := &consoleHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.TimeFormat == "" {
+		h.opts.TimeFormat = "15:04:05.000"
+	}
+	if h.opts.MessageWidth <= 0 {
+		h.opts.MessageWidth = 24
+	}
+	return h
+}
+
+type consoleHandler struct {
+	w    io.Writer
+	opts ConsoleHandlerOptions
+	mu   *sync.Mutex
+	// prefix is the dotted path of the groups opened with WithGroup
+	prefix string
+	groups []string
+	// attrs holds attributes added with WithAttrs, already formatted
+	attrs []byte
+}
+
+func (h *consoleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *consoleHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	if !r.Time.IsZero() {
+		h.colored(&buf, ansiDim, r.Time.Format(h.opts.TimeFormat))
+		buf.WriteByte(' ')
+	}
+	level := r.Level.String()
+	h.colored(&buf, levelColor(r.Level), fmt.Sprintf("%-5s", level))
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+	if pad := h.opts.MessageWidth - len(r.Message); pad > 0 {
+		buf.WriteString(strings.Repeat(" ", pad))
+	}
+
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		h.appendAttr(&buf, "", nil, slog.String(slog.SourceKey, frame.File+":"+strconv.Itoa(frame.Line)))
+	}
+	buf.Write(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&buf, h.prefix, h.groups, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	buf := bytes.NewBuffer(append([]byte(nil), h.attrs...))
+	for _, a := range attrs {
+		clone.appendAttr(buf, h.prefix, h.groups, a)
+	}
+	clone.attrs = buf.Bytes()
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	clone.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &clone
+}
+
+// appendAttr writes " key=value" for a, flattening groups into dotted keys.
+func (h *consoleHandler) appendAttr(buf *bytes.Buffer, prefix string, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, m := range a.Value.Group() {
+			h.appendAttr(buf, prefix, groups, m)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) || a.Key == "" {
+		return
+	}
+	buf.WriteByte(' ')
+	h.colored(buf, ansiCyan, prefix+a.Key+"=")
+	value := consoleValue(a.Value)
+	if _, isErr := a.Value.Any().(error); isErr && a.Value.Kind() == slog.KindAny {
+		h.colored(buf, ansiRed, value)
+		return
+	}
+	buf.WriteString(value)
+}
+
+// colored writes s wrapped in the color, unless colors are disabled.
+func (h *consoleHandler) colored(buf *bytes.Buffer, color, s string) {
+	if h.opts.NoColor {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(ansiReset)
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiGreen
+	default:
+		return ansiBlue
+	}
+}
+
+// consoleValue renders v, quoting strings that would be ambiguous unquoted.
+func consoleValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			s = x.Error()
+		case fmt.Stringer:
+			s = x.String()
+		case []byte:
+			s = string(x)
+		default:
+			if data, err := json.Marshal(x); err == nil {
+				return string(data)
+			}
+			s = fmt.Sprint(x)
+		}
+	default:
+		return v.String()
+	}
+	if needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LogBody renders a request or response body for logs. Encoded bodies (a
+// Content-Encoding other than identity) and binary ones, by Content-Type or
+// sniffed content, become a placeholder such as "<image/png, 5120 bytes>".
+// Text is redacted with r and, when maxBytes > 0, cut to maxBytes with a
+// "...truncated (N bytes)" suffix counting the omitted bytes.
+func LogBody(body []byte, header http.Header, maxBytes int, r *Redactor) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if enc := header.Get("Content-Encoding"); enc != "" && !strings.EqualFold(enc, "identity") {
+		return "<" + enc + ", " + strconv.Itoa(len(body)) + " bytes>"
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	if !IsTextContentType(contentType) || !utf8.Valid(body) {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = "binary"
+		}
+		return "<" + mediaType + ", " + strconv.Itoa(len(body)) + " bytes>"
+	}
+
+	text := r.Body(string(body))
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text
+	}
+	cut := maxBytes
+	// Do not split a multi-byte character
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "...truncated (" + strconv.Itoa(len(text)-cut) + " bytes)"
+}
+
+// IsTextContentType reports whether a media type carries readable text:
+// text/*, JSON, XML, form data and a few other textual application types.
+func IsTextContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-www-form-urlencoded",
+		"application/javascript", "application/graphql", "application/x-ndjson",
+		"application/yaml", "application/x-yaml":
+		return true
+	}
+	return false
+}
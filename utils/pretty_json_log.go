@@ -6,19 +6,42 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
 )
 
+// NewPrettyJSONHandler returns a slog.Handler writing each record as an
+// indented JSON object, for reading logs during development. Groups, from
+// WithGroup or slog.Group, become nested objects. A key repeated within one
+// object is kept with a "#2", "#3"... suffix instead of overwriting the
+// earlier value. opts may be nil; Level, AddSource and ReplaceAttr are
+// honored.
 func NewPrettyJSONHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
 	return &prettyJSONHandler{
-		w:     w,
-		opts:  opts,
-		attrs: []slog.Attr{},
+		w:    w,
+		opts: opts,
+		mu:   &sync.Mutex{},
 	}
 }
 
 type prettyJSONHandler struct {
-	w     io.Writer
-	opts  *slog.HandlerOptions
+	w    io.Writer
+	opts *slog.HandlerOptions
+	// mu serializes writes across handlers derived with WithAttrs/WithGroup
+	mu *sync.Mutex
+	// scopes holds the groups and attributes added by WithGroup and
+	// WithAttrs, in order
+	scopes []handlerScope
+}
+
+// handlerScope is either a group opened by WithGroup or attributes added by
+// WithAttrs within the groups opened before it.
+type handlerScope struct {
+	group string
 	attrs []slog.Attr
 }
 
@@ -30,40 +53,156 @@ func (h *prettyJSONHandler) Enabled(ctx context.Context, level slog.Level) bool
 }
 
 func (h *prettyJSONHandler) Handle(ctx context.Context, r slog.Record) error {
-	var buf bytes.Buffer
-	buf.WriteString("\n")
-	enc := json.NewEncoder(&buf)
-	enc.SetIndent("", "  ")
-
-	m := make(map[string]interface{})
-	m["time"] = r.Time
-	m["level"] = r.Level.String()
-	m["msg"] = r.Message
-
-	// Add handler attributes
-	for _, attr := range h.attrs {
-		m[attr.Key] = attr.Value.Any()
+	root := make(map[string]interface{})
+	builtins := []slog.Attr{
+		slog.Time(slog.TimeKey, r.Time),
+		slog.String(slog.LevelKey, r.Level.String()),
+		slog.String(slog.MessageKey, r.Message),
+	}
+	if r.Time.IsZero() {
+		builtins = builtins[1:]
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		builtins = append(builtins, slog.Any(slog.SourceKey, &slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}))
+	}
+	for _, a := range builtins {
+		h.addAttr(root, nil, a)
 	}
 
-	// Add record attributes
+	// Walk the handler's groups, adding their attributes on the way; the
+	// record's attributes go into the innermost group
+	current, groups := root, []string(nil)
+	for _, s := range h.scopes {
+		if s.group != "" {
+			current = childObject(current, s.group)
+			groups = append(groups, s.group)
+			continue
+		}
+		for _, a := range s.attrs {
+			h.addAttr(current, groups, a)
+		}
+	}
 	r.Attrs(func(a slog.Attr) bool {
-		m[a.Key] = a.Value.Any()
+		h.addAttr(current, groups, a)
 		return true
 	})
+	pruneEmpty(root)
 
-	if err := enc.Encode(m); err != nil {
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
 		return err
 	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	_, err := h.w.Write(buf.Bytes())
 	return err
 }
 
 func (h *prettyJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	newHandler := *h
-	newHandler.attrs = append(newHandler.attrs, attrs...)
-	return &newHandler
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(handlerScope{attrs: attrs})
 }
 
 func (h *prettyJSONHandler) WithGroup(name string) slog.Handler {
-	return h
+	if name == "" {
+		return h
+	}
+	return h.with(handlerScope{group: name})
+}
+
+func (h *prettyJSONHandler) with(s handlerScope) *prettyJSONHandler {
+	clone := *h
+	clone.scopes = append(h.scopes[:len(h.scopes):len(h.scopes)], s)
+	return &clone
+}
+
+// addAttr resolves a, applies ReplaceAttr and stores it in obj, nesting
+// groups as objects.
+func (h *prettyJSONHandler) addAttr(obj map[string]interface{}, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		members := a.Value.Group()
+		if len(members) == 0 {
+			return
+		}
+		target := obj
+		if a.Key != "" {
+			// A group with an empty key is inlined
+			target = childObject(obj, a.Key)
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, m := range members {
+			h.addAttr(target, groups, m)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) || a.Key == "" {
+		return
+	}
+	putUnique(obj, a.Key, jsonValue(a.Value))
+}
+
+// childObject returns the object stored at key in obj, creating it when
+// missing. A non-object value already at key is kept under a suffixed key.
+func childObject(obj map[string]interface{}, key string) map[string]interface{} {
+	if child, ok := obj[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := make(map[string]interface{})
+	putUnique(obj, key, child)
+	return child
+}
+
+// putUnique stores v at key, or at the first free "key#N" when key is taken.
+func putUnique(obj map[string]interface{}, key string, v interface{}) {
+	if _, taken := obj[key]; !taken {
+		obj[key] = v
+		return
+	}
+	for n := 2; ; n++ {
+		k := key + "#" + strconv.Itoa(n)
+		if _, taken := obj[k]; !taken {
+			obj[k] = v
+			return
+		}
+	}
+}
+
+// pruneEmpty drops groups that ended up without attributes.
+func pruneEmpty(obj map[string]interface{}) {
+	for k, v := range obj {
+		if child, ok := v.(map[string]interface{}); ok {
+			pruneEmpty(child)
+			if len(child) == 0 {
+				delete(obj, k)
+			}
+		}
+	}
+}
+
+// jsonValue converts v into a value encoding/json renders sensibly.
+func jsonValue(v slog.Value) interface{} {
+	if v.Kind() == slog.KindAny {
+		// Errors rarely marshal to anything but {}
+		if err, ok := v.Any().(error); ok {
+			if _, ok := err.(json.Marshaler); !ok {
+				return err.Error()
+			}
+		}
+	}
+	return v.Any()
 }
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 9457 problem details object, the structured error
+// body of many modern APIs. Members with an unexpected JSON type are ignored,
+// as the RFC requires.
+type ProblemDetails struct {
+	// Type identifies the problem type; "about:blank" when absent.
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions holds the other members, e.g. "errors" or "balance"; decode
+	// them with json.Unmarshal.
+	Extensions map[string]json.RawMessage
+}
+
+func (p *ProblemDetails) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = p.Type
+	}
+	if p.Status != 0 {
+		msg = fmt.Sprintf("%s (%d)", msg, p.Status)
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return msg
+}
+
+// UnmarshalJSON decodes a problem details object.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if members == nil {
+		return fmt.Errorf("problem details: not a JSON object")
+	}
+	*p = ProblemDetails{Type: "about:blank"}
+	// A member of the wrong type fails to decode and stays unset
+	for name, raw := range members {
+		switch name {
+		case "type":
+			var s string
+			if json.Unmarshal(raw, &s) == nil && s != "" {
+				p.Type = s
+			}
+		case "title":
+			json.Unmarshal(raw, &p.Title)
+		case "status":
+			json.Unmarshal(raw, &p.Status)
+		case "detail":
+			json.Unmarshal(raw, &p.Detail)
+		case "instance":
+			json.Unmarshal(raw, &p.Instance)
+		default:
+			if p.Extensions == nil {
+				p.Extensions = make(map[string]json.RawMessage)
+			}
+			p.Extensions[name] = raw
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the problem with its extensions as top-level members.
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for name, raw := range p.Extensions {
+		members[name] = raw
+	}
+	members["type"] = p.Type
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// ParseProblemDetails decodes body as problem details when header declares
+// application/problem+json. It returns nil for other responses and for
+// bodies that are not a JSON object.
+func ParseProblemDetails(header http.Header, body []byte) *ProblemDetails {
+	if !IsProblemContentType(header.Get("Content-Type")) {
+		return nil
+	}
+	var p ProblemDetails
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
+// IsProblemContentType reports whether contentType is application/problem+json.
+func IsProblemContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == ProblemContentType
+}
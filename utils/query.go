@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EncodeQuery reflects a struct (or pointer to one) into url.Values using
+// `url` field tags, similar to encoding/json:
+//
+//	type ListParams struct {
+//		Limit  int       `url:"limit,omitempty"`
+//		Tags   []string  `url:"tag"`              // tag=a&tag=b
+//		IDs    []int     `url:"ids,comma"`        // ids=1,2
+//		Since  time.Time `url:"since" layout:"2006-01-02"`
+//		Cursor *string   `url:"cursor"`
+//	}
+//
+// Untagged fields use their name; "-" skips a field. Nil pointers are always
+// omitted and zero values with omitempty. Slices repeat the key unless the
+// comma option is given. time.Time uses RFC 3339 unless a layout tag is set,
+// or "unix" / "unixmilli" for epoch numbers. Values implementing
+// encoding.TextMarshaler are encoded with it. Embedded structs are flattened.
+func EncodeQuery(v any) (url.Values, error) {
+	values := make(url.Values)
+	if v == nil {
+		return values, nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return values, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query must be a struct, got %s", rv.Kind())
+	}
+	if err := encodeStruct(values, rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func encodeStruct(values url.Values, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		omitEmpty := hasOption(opts, "omitempty")
+		comma := hasOption(opts, "comma")
+
+		fv := rv.Field(i)
+		if field.Anonymous && tag == "" && indirectType(field.Type).Kind() == reflect.Struct && indirectType(field.Type) != timeType {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := encodeStruct(values, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Pointer || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		layout := field.Tag.Get("layout")
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			parts := make([]string, 0, fv.Len())
+			for j := 0; j < fv.Len(); j++ {
+				s, err := formatQueryValue(fv.Index(j), layout)
+				if err != nil {
+					return fmt.Errorf("query field %s: %w", field.Name, err)
+				}
+				parts = append(parts, s)
+			}
+			if omitEmpty && len(parts) == 0 {
+				continue
+			}
+			if comma {
+				values.Add(name, strings.Join(parts, ","))
+			} else {
+				for _, p := range parts {
+					values.Add(name, p)
+				}
+			}
+			continue
+		}
+
+		s, err := formatQueryValue(fv, layout)
+		if err != nil {
+			return fmt.Errorf("query field %s: %w", field.Name, err)
+		}
+		values.Add(name, s)
+	}
+	return nil
+}
+
+// formatQueryValue renders a scalar query value.
+func formatQueryValue(v reflect.Value, layout string) (string, error) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch layout {
+		case "":
+			return t.Format(time.RFC3339), nil
+		case "unix":
+			return strconv.FormatInt(t.Unix(), 10), nil
+		case "unixmilli":
+			return strconv.FormatInt(t.UnixMilli(), 10), nil
+		default:
+			return t.Format(layout), nil
+		}
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func hasOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces sensitive values in logs.
+const redactedValue = "****"
+
+// DefaultRedactHeaders are masked when a Redactor is built with nil headers.
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"}
+
+// Redactor masks sensitive headers, query parameters and JSON body fields
+// before they are logged. A nil *Redactor leaves everything unchanged.
+type Redactor struct {
+	headers   map[string]bool
+	query     map[string]bool
+	bodyPaths [][]string
+}
+
+// NewRedactor builds a Redactor. Header names are case-insensitive; nil
+// headers selects DefaultRedactHeaders, while an empty slice masks none.
+// Query parameter names are matched exactly. Body paths are dot-separated JSON
+// paths where "*" matches any object key or array element, e.g. "password",
+// "user.credentials.token" or "items.*.secret".
+func NewRedactor(headers, queryParams, bodyJSONPaths []string) *Redactor {
+	if headers == nil {
+		headers = DefaultRedactHeaders
+	}
+	r := &Redactor{
+		headers: make(map[string]bool, len(headers)),
+		query:   make(map[string]bool, len(queryParams)),
+	}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, q := range queryParams {
+		r.query[q] = true
+	}
+	for _, p := range bodyJSONPaths {
+		r.bodyPaths = append(r.bodyPaths, strings.Split(p, "."))
+	}
+	return r
+}
+
+// MaskValue masks a header value, keeping a leading auth scheme so logs still
+// show which kind of credential was sent: "Bearer abc" becomes "Bearer ****".
+func MaskValue(v string) string {
+	if scheme, _, ok := strings.Cut(v, " "); ok && scheme != "" {
+		return scheme + " " + redactedValue
+	}
+	return redactedValue
+}
+
+// Headers returns a copy of h with sensitive values masked.
+func (r *Redactor) Headers(h map[string][]string) map[string][]string {
+	if r == nil || h == nil {
+		return h
+	}
+	out := make(map[string][]string, len(h))
+	for k, values := range h {
+		if !r.headers[http.CanonicalHeaderKey(k)] {
+			out[k] = values
+			continue
+		}
+		masked := make([]string, len(values))
+		for i, v := range values {
+			masked[i] = MaskValue(v)
+		}
+		out[k] = masked
+	}
+	return out
+}
+
+// QueryValues returns a copy of values with sensitive parameters masked.
+func (r *Redactor) QueryValues(values map[string][]string) map[string][]string {
+	if r == nil || len(r.query) == 0 || values == nil {
+		return values
+	}
+	out := make(map[string][]string, len(values))
+	for k, v := range values {
+		if r.query[k] {
+			masked := make([]string, len(v))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			out[k] = masked
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Query masks sensitive parameters in an encoded query string, keeping the
+// order and encoding of everything else.
+func (r *Redactor) Query(rawQuery string) string {
+	if r == nil || len(r.query) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && r.query[name] {
+			pairs[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// URL renders u with sensitive query parameters and any userinfo password
+// masked.
+func (r *Redactor) URL(u *url.URL) string {
+	if r == nil {
+		return u.String()
+	}
+	clone := *u
+	clone.RawQuery = r.Query(u.RawQuery)
+	if _, ok := clone.User.Password(); ok {
+		clone.User = url.User(clone.User.Username())
+		return strings.Replace(clone.String(), "@", ":"+redactedValue+"@", 1)
+	}
+	return clone.String()
+}
+
+// Body masks the configured JSON paths in body. Bodies that are not JSON are
+// returned unchanged.
+func (r *Redactor) Body(body string) string {
+	if r == nil || len(r.bodyPaths) == 0 || body == "" {
+		return body
+	}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	for _, path := range r.bodyPaths {
+		doc = redactPath(doc, path)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactPath masks the value at path within node.
+func redactPath(node interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return redactedValue
+	}
+	key, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if key == "*" || key == k {
+				n[k] = redactPath(v, rest)
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if key == "*" {
+				n[i] = redactPath(v, rest)
+			}
+		}
+	}
+	return node
+}
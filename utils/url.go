@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// JoinURL resolves a request path against a base URL. Unlike ResolveReference
+// and string concatenation it keeps the base path prefix ("/api/v1" + "/posts"
+// yields "/api/v1/posts"), treats path as an already-escaped path so reserved
+// characters survive, and carries over any query string or fragment in path.
+// A path that is itself an absolute URL is returned unchanged, and a nil base
+// simply parses path.
+func JoinURL(base *url.URL, path string) (*url.URL, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil || ref.IsAbs() {
+		return ref, nil
+	}
+
+	// Split off query and fragment so JoinPath only sees the path part
+	rawPath := path
+	if i := strings.IndexAny(rawPath, "?#"); i >= 0 {
+		rawPath = rawPath[:i]
+	}
+
+	joined := base.JoinPath(rawPath)
+	// JoinPath drops a trailing slash on the base when path is empty
+	if rawPath == "" {
+		joined.Path, joined.RawPath = base.Path, base.RawPath
+	}
+
+	joined.RawQuery = base.RawQuery
+	if ref.RawQuery != "" {
+		if joined.RawQuery != "" {
+			joined.RawQuery += "&" + ref.RawQuery
+		} else {
+			joined.RawQuery = ref.RawQuery
+		}
+	}
+	joined.Fragment, joined.RawFragment = ref.Fragment, ref.RawFragment
+	return joined, nil
+}